@@ -98,6 +98,14 @@ func (b *Buffer) Write(bs []byte) (int, error) {
 	return len(bs), nil
 }
 
+// Truncate discards all but the first n bytes of the buffer. It does
+// nothing if n is negative or greater than the length of the buffer.
+func (b *Buffer) Truncate(n int) {
+	if n >= 0 && n < len(b.bs) {
+		b.bs = b.bs[:n]
+	}
+}
+
 // TrimNewline trims any final "\n" byte from the end of the buffer.
 func (b *Buffer) TrimNewline() {
 	if i := len(b.bs) - 1; i >= 0 {
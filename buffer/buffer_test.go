@@ -46,6 +46,8 @@ func TestBufferWrites(t *testing.T) {
 		// Intenationally introduce some floating-point error.
 		{"AppendFloat32", func() { buf.AppendFloat(float64(float32(3.14)), 32) }, "3.14"},
 		{"AppendWrite", func() { buf.Write([]byte("foo")) }, "foo"},
+		{"Truncate", func() { buf.AppendString("foobar"); buf.Truncate(3) }, "foo"},
+		{"TruncateOutOfRange", func() { buf.AppendString("foo"); buf.Truncate(4); buf.Truncate(-1) }, "foo"},
 	}
 
 	for _, tt := range tests {
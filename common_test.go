@@ -21,6 +21,7 @@
 package zap
 
 import (
+	"bytes"
 	"sync"
 	"testing"
 
@@ -28,6 +29,28 @@ import (
 	"github.com/templexxx/zap/zaptest/observer"
 )
 
+// testBuffer is an in-memory WriteSyncer, useful for capturing internal
+// logger errors.
+type testBuffer struct {
+	bytes.Buffer
+	writeErr error
+	closed   bool
+}
+
+func (b *testBuffer) Write(p []byte) (int, error) {
+	if b.writeErr != nil {
+		return 0, b.writeErr
+	}
+	return b.Buffer.Write(p)
+}
+
+func (b *testBuffer) Sync() error   { return nil }
+func (b *testBuffer) ReOpen() error { return nil }
+func (b *testBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
 func opts(opts ...Option) []Option {
 	return opts
 }
@@ -40,6 +63,10 @@ func withLogger(t testing.TB, e zapcore.LevelEnabler, opts []Option, f func(*Log
 	f(log, logs)
 }
 
+func withSugar(t testing.TB, e zapcore.LevelEnabler, opts []Option, f func(*SugaredLogger, *observer.ObservedLogs)) {
+	withLogger(t, e, opts, func(logger *Logger, logs *observer.ObservedLogs) { f(logger.Sugar(), logs) })
+}
+
 func runConcurrently(goroutines, iterations int, wg *sync.WaitGroup, f func()) {
 	wg.Add(goroutines)
 	for g := 0; g < goroutines; g++ {
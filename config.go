@@ -21,11 +21,26 @@
 package zap
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/templexxx/zap/zapcore"
 )
 
+// SamplingConfig sets a sampling strategy for the logger. Sampling caps the
+// global CPU and I/O load that logging puts on your process while attempting
+// to preserve a representative subset of your logs.
+//
+// If specified, the Sampler will invoke the Hook after each decision.
+//
+// Values configured here are per-second. See zapcore.NewSampler for details.
+type SamplingConfig struct {
+	Initial    int                                           `json:"initial" yaml:"initial"`
+	Thereafter int                                           `json:"thereafter" yaml:"thereafter"`
+	Hook       func(zapcore.Entry, zapcore.SamplingDecision) `json:"-" yaml:"-"`
+}
+
 // Config offers a declarative way to construct a logger. It doesn't do
 // anything that can't be done with New, Options, and the various
 // zapcore.WriteSyncer and zapcore.Core wrappers, but it's a simpler way to
@@ -44,6 +59,9 @@ type Config struct {
 	// level, so calling Config.Level.SetLevel will atomically change the log
 	// level of all loggers descended from this config.
 	Level AtomicLevel `json:"level" yaml:"level"`
+	// Development puts the logger in development mode, which changes the
+	// behavior of DPanicLevel and takes stacktraces more liberally.
+	Development bool `json:"development" yaml:"development"`
 	// Encoding sets the logger's encoding. Valid values are "json" and
 	// "console", as well as any third-party encodings registered via
 	// RegisterEncoder.
@@ -52,8 +70,16 @@ type Config struct {
 	// zapcore.EncoderConfig for details.
 	EncoderConfig zapcore.EncoderConfig `json:"encoderConfig" yaml:"encoderConfig"`
 	// OutputPath is a URL or file path to write logging output to.
-	// See Open for details.
+	// See Open for details. File paths may contain strftime-style verbs,
+	// such as "app-%Y%m%d.log"; see zapcore.RotatingBuffer for details.
 	OutputPath string `json:"outputPath" yaml:"outputPath"`
+	// ErrorOutputPath is a URL or file path to write internal logger errors
+	// to. The default is standard error.
+	//
+	// Note that this setting only affects internal errors; for sample code that
+	// sends error-level logs to a different location from info- and debug-level
+	// logs, see the package-level AdvancedConfiguration example.
+	ErrorOutputPath string `json:"errorOutputPath" yaml:"errorOutputPath"`
 
 	// BufSize log write buf,
 	// See zapcore/writebuf.go for details.
@@ -61,6 +87,36 @@ type Config struct {
 
 	// Flush will flush log buf every Flush seconds.
 	Flush int `json:"flush" yaml:"flush"`
+
+	// MaxSize rotates the output file once it grows past MaxSize megabytes.
+	// Zero disables rotation. It's ignored for stdout and stderr.
+	MaxSize int `json:"maxSize" yaml:"maxSize"`
+	// Rotate rotates the output file "hourly" or "daily", in addition to
+	// rotating it by size. It's ignored for stdout and stderr.
+	Rotate string `json:"rotate" yaml:"rotate"`
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of
+	// them.
+	MaxBackups int `json:"maxBackups" yaml:"maxBackups"`
+	// MaxAge is the number of days to keep rotated files for. Zero keeps them
+	// regardless of age.
+	MaxAge int `json:"maxAge" yaml:"maxAge"`
+	// Compress gzips rotated files. Failures to compress or remove rotated
+	// files are reported to ErrorOutputPath.
+	Compress bool `json:"compress" yaml:"compress"`
+
+	// DisableCaller stops annotating logs with the calling function's file
+	// name and line number. By default, all logs are annotated.
+	DisableCaller bool `json:"disableCaller" yaml:"disableCaller"`
+	// DisableStacktrace completely disables automatic stacktrace capturing. By
+	// default, stacktraces are captured for ErrorLevel and above.
+	DisableStacktrace bool `json:"disableStacktrace" yaml:"disableStacktrace"`
+	// Sampling sets a sampling policy. A nil SamplingConfig disables sampling.
+	Sampling *SamplingConfig `json:"sampling" yaml:"sampling"`
+	// Clock is the source of time for logged entries and for rotating the
+	// output file, so that files are named after the entries in them. Tests
+	// may replace it to rotate without waiting. It defaults to the system
+	// clock.
+	Clock zapcore.Clock `json:"-" yaml:"-"`
 }
 
 // Build constructs a logger from the Config and Options.
@@ -70,13 +126,14 @@ func (cfg Config) Build(opts ...Option) (*Logger, error) {
 		return nil, err
 	}
 
-	syncer, err := openSyncer(cfg)
+	syncer, errSink, err := cfg.openSyncers()
 	if err != nil {
 		return nil, err
 	}
 
 	log := New(
 		zapcore.NewCore(enc, syncer, cfg.Level),
+		cfg.buildOptions(errSink)...,
 	)
 	if len(opts) > 0 {
 		log = log.WithOptions(opts...)
@@ -84,27 +141,141 @@ func (cfg Config) Build(opts ...Option) (*Logger, error) {
 	return log, nil
 }
 
+func (cfg Config) buildOptions(errSink zapcore.WriteSyncer) []Option {
+	opts := []Option{ownedErrorOutput(errSink)}
+
+	if cfg.Clock != nil {
+		opts = append(opts, WithClock(cfg.Clock))
+	}
+
+	if cfg.Development {
+		opts = append(opts, Development())
+	}
+
+	if !cfg.DisableCaller {
+		opts = append(opts, AddCaller())
+	}
+
+	stackLevel := ErrorLevel
+	if cfg.Development {
+		stackLevel = WarnLevel
+	}
+	if !cfg.DisableStacktrace {
+		opts = append(opts, AddStacktrace(stackLevel))
+	}
+
+	if scfg := cfg.Sampling; scfg != nil {
+		opts = append(opts, WrapCore(func(core zapcore.Core) zapcore.Core {
+			var samplerOpts []zapcore.SamplerOption
+			if scfg.Hook != nil {
+				samplerOpts = append(samplerOpts, zapcore.SamplerHook(scfg.Hook))
+			}
+			return zapcore.NewSampler(
+				core,
+				time.Second,
+				cfg.Sampling.Initial,
+				cfg.Sampling.Thereafter,
+				samplerOpts...,
+			)
+		}))
+	}
+
+	return opts
+}
+
 func (cfg Config) buildEncoder() (zapcore.Encoder, error) {
 	return newEncoder(cfg.Encoding, cfg.EncoderConfig)
 }
 
 const defaultFlush = 5
 
-func openSyncer(cfg Config) (zapcore.WriteSyncer, error) {
+func (cfg Config) openSyncers() (sink, errSink zapcore.WriteSyncer, err error) {
+	errSink, err = openErrorSyncer(cfg.ErrorOutputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink, err = openSyncer(cfg, errSink)
+	if err != nil {
+		errSink.Close()
+		return nil, nil, err
+	}
+	return sink, errSink, nil
+}
+
+// openErrorSyncer opens an unbuffered WriteSyncer, internal errors are rare
+// and should reach their destination immediately.
+func openErrorSyncer(path string) (zapcore.WriteSyncer, error) {
+	switch path {
+	case "", "stderr":
+		return zapcore.Lock(nopReOpenSyner{os.Stderr}), nil
+	case "stdout":
+		return zapcore.Lock(nopReOpenSyner{os.Stdout}), nil
+	default:
+		f, err := openAppend(path)
+		if err != nil {
+			return nil, err
+		}
+		return zapcore.Lock(&reOpenFile{path: path, File: f}), nil
+	}
+}
+
+func openAppend(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// reOpenFile is an unbuffered file whose ReOpen opens path again, after it's
+// been moved by logrotate, for example.
+type reOpenFile struct {
+	path string
+	*os.File
+}
+
+func (f *reOpenFile) ReOpen() error {
+	nf, err := openAppend(f.path)
+	if err != nil {
+		return err
+	}
+	old := f.File
+	f.File = nf
+	return old.Close()
+}
+
+func openSyncer(cfg Config, errSink zapcore.WriteSyncer) (zapcore.WriteSyncer, error) {
 	switch cfg.OutputPath {
 	case "stdout":
 		return zapcore.Lock(nopReOpenSyner{os.Stdout}), nil
 	case "stderr":
 		return zapcore.Lock(nopReOpenSyner{os.Stderr}), nil
 	default:
-		f, err := os.OpenFile(cfg.OutputPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
-		if err != nil {
-			return nil, err
+		var interval zapcore.RotateInterval
+		switch cfg.Rotate {
+		case "":
+		case "hourly":
+			interval = zapcore.RotateHourly
+		case "daily":
+			interval = zapcore.RotateDaily
+		default:
+			return nil, fmt.Errorf("unknown rotation interval %q", cfg.Rotate)
 		}
 		if cfg.Flush == 0 {
 			cfg.Flush = 5
 		}
-		return zapcore.Buffer(f, cfg.BufSize, cfg.Flush, cfg.OutputPath), nil
+		clock := cfg.Clock
+		if clock == nil {
+			clock = zapcore.DefaultClock
+		}
+		return zapcore.RotatingBuffer(cfg.OutputPath, cfg.BufSize, cfg.Flush, zapcore.RotateConfig{
+			MaxSize:    int64(cfg.MaxSize) * 1024 * 1024,
+			Interval:   interval,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     time.Duration(cfg.MaxAge) * 24 * time.Hour,
+			Compress:   cfg.Compress,
+			Clock:      clock,
+			ErrorHandler: func(err error) {
+				fmt.Fprintf(errSink, "%v rotation error: %v\n", clock.Now().UTC(), err)
+				errSink.Sync()
+			},
+		})
 	}
 }
 
@@ -116,12 +287,21 @@ func (nopReOpenSyner) ReOpen() error {
 	return nil
 }
 
+// Close leaves the standard streams open for the rest of the process.
+func (s nopReOpenSyner) Close() error {
+	if s.File == os.Stdout || s.File == os.Stderr {
+		return nil
+	}
+	return s.File.Close()
+}
+
 func DefaultConfig() Config {
 	return Config{
-		Level:         NewAtomicLevelAt(InfoLevel),
-		Encoding:      "json",
-		EncoderConfig: DefaultEncoderConf(),
-		OutputPath:    "stderr",
+		Level:           NewAtomicLevelAt(InfoLevel),
+		Encoding:        "json",
+		EncoderConfig:   DefaultEncoderConf(),
+		OutputPath:      "stderr",
+		ErrorOutputPath: "stderr",
 	}
 }
 
@@ -129,10 +309,50 @@ func DefaultEncoderConf() zapcore.EncoderConfig {
 	return zapcore.EncoderConfig{
 		TimeKey:        "time",
 		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
 		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
 		LineEnding:     zapcore.DefaultLineEnding,
 		EncodeLevel:    zapcore.LowercaseLevelEncoder,
 		EncodeTime:     zapcore.ISO8601TimeEncoder,
 		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// NewDevelopmentConfig is a reasonable development logging configuration.
+// Logging is enabled at DebugLevel and above.
+//
+// It enables development mode (which makes DPanicLevel logs panic), uses a
+// console encoder and writes to standard error.
+// Stacktraces are automatically included on logs of WarnLevel and above.
+func NewDevelopmentConfig() Config {
+	return Config{
+		Level:           NewAtomicLevelAt(DebugLevel),
+		Development:     true,
+		Encoding:        "console",
+		EncoderConfig:   NewDevelopmentEncoderConfig(),
+		OutputPath:      "stderr",
+		ErrorOutputPath: "stderr",
+	}
+}
+
+// NewDevelopmentEncoderConfig returns an opinionated EncoderConfig for
+// development environments.
+func NewDevelopmentEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		// Keys can be anything except the empty string.
+		TimeKey:        "T",
+		LevelKey:       "L",
+		NameKey:        "N",
+		CallerKey:      "C",
+		MessageKey:     "M",
+		StacktraceKey:  "S",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 }
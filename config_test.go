@@ -0,0 +1,196 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/templexxx/zap/zapcore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+func TestConfigSampling(t *testing.T) {
+	temp, err := ioutil.TempFile("", "zap-config-sampling")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(temp.Name())
+	temp.Close()
+
+	var sampled, dropped atomic.Int64
+	cfg := DefaultConfig()
+	cfg.OutputPath = temp.Name()
+	cfg.Sampling = &SamplingConfig{
+		Initial:    2,
+		Thereafter: 3,
+		Hook: func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped > 0 {
+				dropped.Inc()
+			} else if dec&zapcore.LogSampled > 0 {
+				sampled.Inc()
+			}
+		},
+	}
+
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	for i := 0; i < 10; i++ {
+		logger.Info("hot loop")
+	}
+	require.NoError(t, logger.Sync(), "Unexpected error syncing logger.")
+
+	byteContents, err := ioutil.ReadFile(temp.Name())
+	require.NoError(t, err, "Couldn't read log contents from temp file.")
+	lines := strings.Split(strings.TrimSpace(string(byteContents)), "\n")
+	assert.Equal(t, 4, len(lines), "Unexpected number of sampled lines.")
+	assert.Equal(t, int64(4), sampled.Load(), "Unexpected number of sampled entries.")
+	assert.Equal(t, int64(6), dropped.Load(), "Unexpected number of dropped entries.")
+}
+
+func TestConfigClose(t *testing.T) {
+	temp, err := ioutil.TempFile("", "zap-config-close")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(temp.Name())
+	temp.Close()
+
+	before := runtime.NumGoroutine()
+	cfg := DefaultConfig()
+	cfg.OutputPath = temp.Name()
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	logger.Info("closing")
+
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+	assert.NoError(t, logger.Close(), "Expected closing again to be a no-op.")
+	assert.Equal(t, before, runtime.NumGoroutine(), "Expected Close to stop background goroutines.")
+
+	byteContents, err := ioutil.ReadFile(temp.Name())
+	require.NoError(t, err, "Couldn't read log contents from temp file.")
+	assert.Contains(t, string(byteContents), "closing", "Expected Close to flush buffered entries.")
+}
+
+func TestConfigCloseErrorOutput(t *testing.T) {
+	temp, err := ioutil.TempFile("", "zap-config-close-errors")
+	require.NoError(t, err, "Failed to create temp file.")
+	defer os.Remove(temp.Name())
+	temp.Close()
+
+	cfg := DefaultConfig()
+	cfg.ErrorOutputPath = temp.Name()
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	errorOutput := logger.errorOutput
+
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+	_, err = errorOutput.Write([]byte("late\n"))
+	assert.Error(t, err, "Expected Close to close the error output Config opened.")
+
+	errBuf := &testBuffer{}
+	logger, err = cfg.Build(ErrorOutput(errBuf))
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+	assert.False(t, errBuf.closed, "Expected an error output supplied as an Option to be left open.")
+}
+
+type settableClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *settableClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *settableClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+func (c *settableClock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+func TestConfigClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config-clock")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &settableClock{now: time.Date(2026, 10, 17, 23, 59, 0, 0, time.UTC)}
+	cfg := DefaultConfig()
+	cfg.OutputPath = filepath.Join(dir, "app-%Y%m%d.log")
+	cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	cfg.DisableCaller = true
+	cfg.Clock = clock
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+
+	logger.Info("first")
+	clock.Set(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	logger.Info("second")
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+
+	for name, want := range map[string]string{
+		"app-20261017.log": `"time":"2026-10-17T23:59:00.000Z","msg":"first"`,
+		"app-20261018.log": `"time":"2026-10-18T00:00:00.000Z","msg":"second"`,
+	} {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, "Expected %s to exist.", name)
+		assert.Contains(t, string(contents), want, "Expected entries in files named after their time.")
+	}
+}
+
+func TestConfigRotationErrorsUseClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-config-clock")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	// A directory in the way of the compressed file makes compression fail.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app-20261017.log.gz"), 0755), "Failed to create directory.")
+
+	clock := &settableClock{now: time.Date(2026, 10, 17, 23, 59, 0, 0, time.UTC)}
+	cfg := DefaultConfig()
+	cfg.OutputPath = filepath.Join(dir, "app-%Y%m%d.log")
+	cfg.ErrorOutputPath = filepath.Join(dir, "errors.log")
+	cfg.Compress = true
+	cfg.Clock = clock
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+
+	logger.Info("first")
+	clock.Set(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC))
+	logger.Info("second")
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+
+	errors, err := ioutil.ReadFile(cfg.ErrorOutputPath)
+	require.NoError(t, err, "Failed to read the error output.")
+	assert.Contains(t, string(errors), "2026-10-18 00:00:00 +0000 UTC rotation error:", "Expected rotation errors to be stamped by the clock.")
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import "context"
+
+type ctxLoggerKey struct{}
+
+// A ContextExtractor pulls request-scoped fields, such as request IDs or
+// tenant IDs, out of a context.Context. It should return nil if the context
+// carries nothing of interest.
+type ContextExtractor func(context.Context) []Field
+
+// ToContext returns a copy of ctx that carries the Logger. Use FromContext to
+// retrieve it further down the call stack.
+func ToContext(ctx context.Context, log *Logger) context.Context {
+	return context.WithValue(ctx, ctxLoggerKey{}, log)
+}
+
+// FromContext returns the Logger stored in ctx by ToContext. If ctx carries no
+// Logger, the global Logger returned by L is used instead.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if log, ok := ctx.Value(ctxLoggerKey{}).(*Logger); ok && log != nil {
+			return log
+		}
+	}
+	return L()
+}
+
+// DebugCtx logs a message at DebugLevel, like Debug. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+func (log *Logger) DebugCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(DebugLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// InfoCtx logs a message at InfoLevel, like Info. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+func (log *Logger) InfoCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(InfoLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// WarnCtx logs a message at WarnLevel, like Warn. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+func (log *Logger) WarnCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(WarnLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// ErrorCtx logs a message at ErrorLevel, like Error. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+func (log *Logger) ErrorCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(ErrorLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// DPanicCtx logs a message at DPanicLevel, like DPanic. Fields pulled out of
+// ctx by the Logger's ContextExtractors are added before the fields passed at
+// the log site.
+func (log *Logger) DPanicCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(DPanicLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// PanicCtx logs a message at PanicLevel, like Panic. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+//
+// The logger then panics, even if logging at PanicLevel is disabled.
+func (log *Logger) PanicCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(PanicLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+// FatalCtx logs a message at FatalLevel, like Fatal. Fields pulled out of ctx
+// by the Logger's ContextExtractors are added before the fields passed at the
+// log site.
+//
+// The logger then calls os.Exit(1), even if logging at FatalLevel is
+// disabled.
+func (log *Logger) FatalCtx(ctx context.Context, msg string, fields ...Field) {
+	if ce := log.check(FatalLevel, msg); ce != nil {
+		ce.Write(log.contextFields(ctx, fields)...)
+	}
+}
+
+func (log *Logger) contextFields(ctx context.Context, fields []Field) []Field {
+	if ctx == nil || len(log.extractors) == 0 {
+		return fields
+	}
+
+	var all []Field
+	for _, extract := range log.extractors {
+		all = append(all, extract(ctx)...)
+	}
+	if len(all) == 0 {
+		return fields
+	}
+	return append(all, fields...)
+}
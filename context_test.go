@@ -0,0 +1,132 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/templexxx/zap/internal/exit"
+	"github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type requestIDKey struct{}
+
+func extractRequestID(ctx context.Context) []Field {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return []Field{String("requestID", id)}
+	}
+	return nil
+}
+
+func TestContextRoundTrip(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		ctx := ToContext(context.Background(), logger)
+		assert.Equal(t, logger, FromContext(ctx), "Expected to get back the stored logger.")
+
+		FromContext(ctx).Info("hello")
+		assert.Equal(t, 1, logs.Len(), "Expected the stored logger to be used.")
+	})
+}
+
+func TestFromContextFallsBackToGlobal(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		defer ReplaceGlobals(logger)()
+		for _, ctx := range []context.Context{nil, context.Background()} {
+			assert.Equal(t, logger, FromContext(ctx), "Expected to fall back to the global logger.")
+		}
+	})
+}
+
+func TestLoggerContextExtractors(t *testing.T) {
+	tenant := func(ctx context.Context) []Field {
+		return []Field{String("tenant", "acme")}
+	}
+	options := opts(ContextExtractors(extractRequestID), ContextExtractors(tenant))
+	withLogger(t, DebugLevel, options, func(logger *Logger, logs *observer.ObservedLogs) {
+		ctx := context.WithValue(context.Background(), requestIDKey{}, "42")
+		methods := []struct {
+			f   func(context.Context, string, ...Field)
+			lvl zapcore.Level
+		}{
+			{logger.DebugCtx, DebugLevel},
+			{logger.InfoCtx, InfoLevel},
+			{logger.WarnCtx, WarnLevel},
+			{logger.ErrorCtx, ErrorLevel},
+			{logger.DPanicCtx, DPanicLevel},
+		}
+		for _, m := range methods {
+			m.f(ctx, "msg", Int("n", 1))
+		}
+
+		output := logs.AllUntimed()
+		assert.Equal(t, len(methods), len(output), "Unexpected number of logs.")
+		for i, m := range methods {
+			assert.Equal(t, observer.LoggedEntry{
+				Entry:   zapcore.Entry{Level: m.lvl, Message: "msg"},
+				Context: []Field{String("requestID", "42"), String("tenant", "acme"), Int("n", 1)},
+			}, output[i], "Unexpected output from %s-level context method.", m.lvl)
+		}
+	})
+}
+
+func TestLoggerContextExtractorsNotShared(t *testing.T) {
+	withLogger(t, DebugLevel, opts(ContextExtractors(extractRequestID)), func(logger *Logger, logs *observer.ObservedLogs) {
+		ctx := context.WithValue(context.Background(), requestIDKey{}, "42")
+		other := func(context.Context) []Field { return []Field{Bool("other", true)} }
+
+		child := logger.WithOptions(ContextExtractors(other))
+		logger.InfoCtx(ctx, "")
+		child.InfoCtx(ctx, "")
+		logger.InfoCtx(nil, "")
+
+		assert.Equal(t, []observer.LoggedEntry{
+			{Entry: zapcore.Entry{Level: InfoLevel}, Context: []Field{String("requestID", "42")}},
+			{Entry: zapcore.Entry{Level: InfoLevel}, Context: []Field{String("requestID", "42"), Bool("other", true)}},
+			{Entry: zapcore.Entry{Level: InfoLevel}, Context: []Field{}},
+		}, logs.AllUntimed(), "Unexpected cross-talk between extractors.")
+	})
+}
+
+func TestLoggerContextTerminalMethods(t *testing.T) {
+	withLogger(t, DebugLevel, opts(ContextExtractors(extractRequestID)), func(logger *Logger, logs *observer.ObservedLogs) {
+		ctx := context.WithValue(context.Background(), requestIDKey{}, "42")
+		assert.Panics(t, func() { logger.PanicCtx(ctx, "") }, "Expected PanicCtx to panic.")
+		stub := exit.WithStub(func() { logger.FatalCtx(ctx, "") })
+		assert.True(t, stub.Exited, "Expected FatalCtx to terminate process.")
+
+		for _, obs := range logs.AllUntimed() {
+			assert.Equal(t, []Field{String("requestID", "42")}, obs.Context, "Expected extracted fields.")
+		}
+	})
+}
+
+func TestLoggerContextCaller(t *testing.T) {
+	withLogger(t, DebugLevel, opts(AddCaller()), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.InfoCtx(context.Background(), "")
+		output := logs.AllUntimed()
+		assert.Equal(t, 1, len(output), "Unexpected number of logs written out.")
+		assert.Regexp(t, `.+/context_test.go:[\d]+$`, output[0].Entry.Caller, "Unexpected caller.")
+	})
+}
@@ -149,3 +149,11 @@ func TestFieldConstructors(t *testing.T) {
 		assertCanBeReused(t, tt.field)
 	}
 }
+
+func TestStackField(t *testing.T) {
+	f := Stack("stacktrace")
+	assert.Equal(t, "stacktrace", f.Key, "Unexpected field key.")
+	assert.Equal(t, zapcore.StringType, f.Type, "Unexpected field type.")
+	assert.Equal(t, takeStacktrace(), f.String, "Unexpected stack trace")
+	assertCanBeReused(t, f)
+}
@@ -1,5 +1,7 @@
 module github.com/templexxx/zap
 
+go 1.13
+
 require (
 	github.com/pkg/errors v0.8.1
 	github.com/stretchr/testify v1.3.0
@@ -21,9 +21,15 @@
 package zap
 
 import (
-	"time"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
 
 	"github.com/templexxx/zap/zapcore"
+
+	"go.uber.org/multierr"
 )
 
 // A Logger provides fast, leveled, structured logging. All methods are safe
@@ -35,10 +41,27 @@ import (
 // better balance between performance and ergonomics.
 type Logger struct {
 	core zapcore.Core
+
+	development bool
+	name        string
+	errorOutput zapcore.WriteSyncer
+	// ownsErrorOutput is set when errorOutput was opened by Config.Build,
+	// rather than supplied by the caller, so Close should close it.
+	ownsErrorOutput bool
+
+	addCaller  bool
+	addStack   zapcore.LevelEnabler
+	callerSkip int
+
+	extractors []ContextExtractor
+
+	clock zapcore.Clock
 }
 
+// ReOpen reopens the underlying Core's output and the error output, so that
+// they move on to new files once the old ones have been rotated away.
 func (l *Logger) ReOpen() error {
-	return l.core.ReOpen()
+	return multierr.Append(l.core.ReOpen(), l.errorOutput.ReOpen())
 }
 
 // New constructs a new Logger from the provided zapcore.Core and Options. If
@@ -53,12 +76,43 @@ func (l *Logger) ReOpen() error {
 // For sample code, see the package-level AdvancedConfiguration example.
 func New(core zapcore.Core, options ...Option) *Logger {
 
+	if core == nil {
+		return NewNop()
+	}
 	log := &Logger{
-		core: core,
+		core:        core,
+		errorOutput: zapcore.Lock(nopReOpenSyner{os.Stderr}),
+		addStack:    zapcore.FatalLevel + 1,
+		clock:       zapcore.DefaultClock,
 	}
 	return log.WithOptions(options...)
 }
 
+// Sugar wraps the Logger to provide a more ergonomic, but slightly slower,
+// API. Sugaring a Logger is quite inexpensive, so it's reasonable for a
+// single application to use both Loggers and SugaredLoggers, converting
+// between them on the boundaries of performance-sensitive code.
+func (log *Logger) Sugar() *SugaredLogger {
+	core := log.clone()
+	core.callerSkip += 2
+	return &SugaredLogger{core}
+}
+
+// Named adds a new path segment to the logger's name. Segments are joined by
+// periods. By default, Loggers are unnamed.
+func (log *Logger) Named(s string) *Logger {
+	if s == "" {
+		return log
+	}
+	l := log.clone()
+	if log.name == "" {
+		l.name = s
+	} else {
+		l.name = strings.Join([]string{l.name, s}, ".")
+	}
+	return l
+}
+
 // WithOptions clones the current Logger, applies the supplied Options, and
 // returns the resulting Logger. It's safe to use concurrently.
 func (log *Logger) WithOptions(opts ...Option) *Logger {
@@ -87,6 +141,19 @@ func (log *Logger) Check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 	return log.check(lvl, msg)
 }
 
+// Log logs a message at the specified level. The message includes any fields
+// passed at the log site, as well as any fields accumulated on the logger.
+// Any Fields that require evaluation (such as Objects) are evaluated upon
+// invocation of Log.
+//
+// Like the leveled methods, Log panics at PanicLevel (and at DPanicLevel in
+// development) and exits at FatalLevel.
+func (log *Logger) Log(lvl zapcore.Level, msg string, fields ...Field) {
+	if ce := log.check(lvl, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
 // Debug logs a message at DebugLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 func (log *Logger) Debug(msg string, fields ...Field) {
@@ -119,6 +186,18 @@ func (log *Logger) Error(msg string, fields ...Field) {
 	}
 }
 
+// DPanic logs a message at DPanicLevel. The message includes any fields
+// passed at the log site, as well as any fields accumulated on the logger.
+//
+// If the logger is in development mode, it then panics (DPanic means
+// "development panic"). This is useful for catching errors that are
+// recoverable, but shouldn't ever happen.
+func (log *Logger) DPanic(msg string, fields ...Field) {
+	if ce := log.check(DPanicLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
 // Panic logs a message at PanicLevel. The message includes any fields passed
 // at the log site, as well as any fields accumulated on the logger.
 //
@@ -146,6 +225,28 @@ func (log *Logger) Sync() error {
 	return log.core.Sync()
 }
 
+// Close flushes any buffered log entries, stops the underlying Core's
+// background work and closes its output. An error output opened by
+// Config.Build is closed too, but one supplied with ErrorOutput is left to
+// the caller. Loggers derived with With, Named or WithOptions share them, so
+// Close should be called once the whole family is done logging. Calling it
+// again is a no-op.
+func (log *Logger) Close() error {
+	err := log.core.Close()
+	if log.ownsErrorOutput {
+		err = multierr.Append(err, log.errorOutput.Close())
+	}
+	return err
+}
+
+// Level reports the minimum enabled level for this logger, found by probing
+// the underlying Core's Enabled method.
+//
+// For NopLoggers, this is zapcore.InvalidLevel.
+func (log *Logger) Level() zapcore.Level {
+	return zapcore.LevelOf(log.core)
+}
+
 // Core returns the Logger's underlying zapcore.Core.
 func (log *Logger) Core() zapcore.Core {
 	return log.core
@@ -164,15 +265,20 @@ func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 	// Create basic checked entry thru the core; this will be non-nil if the
 	// log message will actually be written somewhere.
 	ent := zapcore.Entry{
-		Time:    time.Now(),
-		Level:   lvl,
-		Message: msg,
+		LoggerName: log.name,
+		Time:       log.clock.Now(),
+		Level:      lvl,
+		Message:    msg,
 	}
 	ce := log.core.Check(ent, nil)
 	willWrite := ce != nil
 
 	// Set up any required terminal behavior.
 	switch ent.Level {
+	case zapcore.DPanicLevel:
+		if log.development {
+			ce = ce.Should(ent, zapcore.WriteThenPanic)
+		}
 	case zapcore.PanicLevel:
 		ce = ce.Should(ent, zapcore.WriteThenPanic)
 	case zapcore.FatalLevel:
@@ -186,12 +292,28 @@ func (log *Logger) check(lvl zapcore.Level, msg string) *zapcore.CheckedEntry {
 		return ce
 	}
 
+	// Thread the error output through to the CheckedEntry.
+	ce.ErrorOutput = log.errorOutput
+	if log.addCaller {
+		ce.Entry.Caller = zapcore.NewEntryCaller(runtime.Caller(log.callerSkip + callerSkipOffset))
+		if !ce.Entry.Caller.Defined {
+			fmt.Fprintf(log.errorOutput, "%v Logger.check error: failed to get caller\n", log.clock.Now().UTC())
+			log.errorOutput.Sync()
+		}
+	}
+	if log.addStack.Enabled(ce.Entry.Level) {
+		ce.Entry.Stack = Stack("").String
+	}
+
 	return ce
 }
 
 func NewNop() *Logger {
 	return &Logger{
-		core: zapcore.NewNopCore(),
+		core:        zapcore.NewNopCore(),
+		errorOutput: zapcore.AddSync(ioutil.Discard),
+		addStack:    zapcore.FatalLevel + 1,
+		clock:       zapcore.DefaultClock,
 	}
 }
 
@@ -206,3 +328,11 @@ func NewExample() *Logger {
 func NewProduction() (*Logger, error) {
 	return DefaultConfig().Build()
 }
+
+// NewDevelopment builds a development Logger that writes DebugLevel and above
+// logs to standard error in a human-friendly format.
+//
+// It's a shortcut for NewDevelopmentConfig().Build(...Option).
+func NewDevelopment(options ...Option) (*Logger, error) {
+	return NewDevelopmentConfig().Build(options...)
+}
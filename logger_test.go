@@ -21,14 +21,19 @@
 package zap
 
 import (
+	"errors"
+	"os"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/templexxx/zap/internal/exit"
 	"github.com/templexxx/zap/zapcore"
 	"github.com/templexxx/zap/zaptest/observer"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 )
 
@@ -110,6 +115,56 @@ func TestLoggerWith(t *testing.T) {
 	})
 }
 
+func TestLoggerNames(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{""}, ""},
+		{[]string{"foo"}, "foo"},
+		{[]string{"foo", ""}, "foo"},
+		{[]string{"foo", "bar"}, "foo.bar"},
+		{[]string{"foo.bar", "baz"}, "foo.bar.baz"},
+		// Garbage in, garbage out.
+		{[]string{"foo.", "bar"}, "foo..bar"},
+		{[]string{"foo", ".bar"}, "foo..bar"},
+		{[]string{"foo.", ".bar"}, "foo...bar"},
+	}
+
+	for _, tt := range tests {
+		withLogger(t, DebugLevel, nil, func(log *Logger, logs *observer.ObservedLogs) {
+			for _, n := range tt.names {
+				log = log.Named(n)
+			}
+			log.Info("")
+			require.Equal(t, 1, logs.Len(), "Expected only one log entry to be written.")
+			assert.Equal(t, tt.expected, logs.AllUntimed()[0].Entry.LoggerName, "Unexpected logger name.")
+		})
+	}
+}
+
+func TestLoggerDPanic(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		assert.NotPanics(t, func() { logger.DPanic("") })
+		assert.Equal(
+			t,
+			[]observer.LoggedEntry{{Entry: zapcore.Entry{Level: DPanicLevel}, Context: []Field{}}},
+			logs.AllUntimed(),
+			"Unexpected log output from DPanic in production mode.",
+		)
+	})
+	withLogger(t, DebugLevel, opts(Development()), func(logger *Logger, logs *observer.ObservedLogs) {
+		assert.Panics(t, func() { logger.DPanic("") })
+		assert.Equal(
+			t,
+			[]observer.LoggedEntry{{Entry: zapcore.Entry{Level: DPanicLevel}, Context: []Field{}}},
+			logs.AllUntimed(),
+			"Unexpected log output from DPanic in development mode.",
+		)
+	})
+}
+
 func TestLoggerLogPanic(t *testing.T) {
 	for _, tt := range []struct {
 		do       func(*Logger)
@@ -190,6 +245,56 @@ func TestLoggerLeveledMethods(t *testing.T) {
 	})
 }
 
+func TestLoggerLog(t *testing.T) {
+	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
+		levels := []zapcore.Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel}
+		for i, lvl := range levels {
+			logger.Log(lvl, "", Int("attempt", i))
+		}
+		output := logs.AllUntimed()
+		assert.Equal(t, len(levels), len(output), "Unexpected number of logs.")
+		for i, lvl := range levels {
+			assert.Equal(
+				t,
+				observer.LoggedEntry{Entry: zapcore.Entry{Level: lvl}, Context: []Field{Int("attempt", i)}},
+				output[i],
+				"Unexpected output from Log at %s.", lvl,
+			)
+		}
+
+		assert.Panics(t, func() { logger.Log(PanicLevel, "") }, "Expected Log at PanicLevel to panic.")
+		stub := exit.WithStub(func() { logger.Log(FatalLevel, "") })
+		assert.True(t, stub.Exited, "Expected Log at FatalLevel to terminate process.")
+	})
+}
+
+func TestLoggerLogCaller(t *testing.T) {
+	withLogger(t, DebugLevel, opts(AddCaller()), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.Log(InfoLevel, "")
+		output := logs.AllUntimed()
+		require.Equal(t, 1, len(output), "Unexpected number of logs written out.")
+		assert.Regexp(t, `.+/logger_test.go:[\d]+$`, output[0].Entry.Caller, "Unexpected caller.")
+	})
+}
+
+func TestLoggerLevel(t *testing.T) {
+	levels := []zapcore.Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel, PanicLevel, FatalLevel}
+	for _, lvl := range levels {
+		withLogger(t, lvl, nil, func(logger *Logger, _ *observer.ObservedLogs) {
+			assert.Equal(t, lvl, logger.Level(), "Unexpected logger level.")
+		})
+	}
+
+	dl := NewAtomicLevelAt(WarnLevel)
+	withLogger(t, dl, nil, func(logger *Logger, _ *observer.ObservedLogs) {
+		assert.Equal(t, WarnLevel, logger.Level(), "Unexpected level before SetLevel.")
+		dl.SetLevel(DebugLevel)
+		assert.Equal(t, DebugLevel, logger.Level(), "Expected level to follow the AtomicLevel.")
+	})
+
+	assert.Equal(t, zapcore.InvalidLevel, NewNop().Level(), "Expected no-op logger to have no enabled level.")
+}
+
 func TestLoggerAlwaysPanics(t *testing.T) {
 	// Users can disable writing out panic-level logs, but calls to logger.Panic()
 	// should still call panic().
@@ -244,6 +349,173 @@ func TestLoggerHooks(t *testing.T) {
 	assert.Equal(t, int64(2), seen.Load(), "Hook saw an unexpected number of logs.")
 }
 
+func TestLoggerAddCaller(t *testing.T) {
+	tests := []struct {
+		options []Option
+		pat     string
+	}{
+		{opts(), `^undefined$`},
+		{opts(WithCaller(false)), `^undefined$`},
+		{opts(AddCaller()), `.+/logger_test.go:[\d]+$`},
+		{opts(AddCaller(), WithCaller(false)), `^undefined$`},
+		{opts(WithCaller(true)), `.+/logger_test.go:[\d]+$`},
+		{opts(WithCaller(true), WithCaller(false)), `^undefined$`},
+		{opts(AddCaller(), AddCallerSkip(1), AddCallerSkip(-1)), `.+/logger_test.go:[\d]+$`},
+		{opts(AddCaller(), AddCallerSkip(1)), `.+/common_test.go:[\d]+$`},
+		{opts(AddCaller(), AddCallerSkip(1), AddCallerSkip(3)), `.+/src/runtime/.*:[\d]+$`},
+	}
+	for _, tt := range tests {
+		withLogger(t, DebugLevel, tt.options, func(logger *Logger, logs *observer.ObservedLogs) {
+			logger.Info("")
+			output := logs.AllUntimed()
+			assert.Equal(t, 1, len(output), "Unexpected number of logs written out.")
+			assert.Regexp(
+				t,
+				tt.pat,
+				output[0].Entry.Caller,
+				"Expected to find package name and file name in output.",
+			)
+		})
+	}
+}
+
+func TestLoggerAddStacktrace(t *testing.T) {
+	assertHasStack := func(t testing.TB, obs observer.LoggedEntry) {
+		// Frames inside this package (including the tests) are trimmed, so the
+		// trace starts at the test runner.
+		assert.Contains(t, obs.Entry.Stack, "testing.tRunner", "Expected to find test runner in stacktrace.")
+		assert.NotContains(t, obs.Entry.Stack, "zap.(*Logger).check", "Expected zap frames to be trimmed.")
+	}
+	withLogger(t, DebugLevel, opts(AddStacktrace(InfoLevel)), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.Debug("")
+		assert.Empty(
+			t,
+			logs.AllUntimed()[0].Entry.Stack,
+			"Unexpected stacktrace at DebugLevel.",
+		)
+
+		logger.Info("")
+		assertHasStack(t, logs.AllUntimed()[1])
+
+		logger.Warn("")
+		assertHasStack(t, logs.AllUntimed()[2])
+	})
+}
+
+func TestLoggerAddCallerFail(t *testing.T) {
+	errBuf := &testBuffer{}
+	withLogger(t, DebugLevel, opts(AddCaller(), ErrorOutput(errBuf)), func(log *Logger, logs *observer.ObservedLogs) {
+		log.callerSkip = 1e3
+		log.Info("Failure.")
+		assert.Regexp(
+			t,
+			`Logger.check error: failed to get caller`,
+			errBuf.String(),
+			"Didn't find expected failure message.",
+		)
+		assert.Equal(
+			t,
+			logs.AllUntimed()[0].Entry.Message,
+			"Failure.",
+			"Expected original message to survive failures in runtime.Caller.")
+	})
+}
+
+func TestLoggerWriteFailure(t *testing.T) {
+	errBuf := &testBuffer{}
+	failing := &testBuffer{writeErr: errors.New("disk full")}
+	logger := New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(DefaultEncoderConf()),
+			failing,
+			DebugLevel,
+		),
+		ErrorOutput(errBuf),
+	)
+
+	logger.Info("foo")
+	// Should log the error.
+	assert.Regexp(t, `write error: disk full`, errBuf.String(), "Expected to log the error to the error output.")
+}
+
+func TestLoggerCloseLeavesSuppliedOutputsOpen(t *testing.T) {
+	errBuf := &testBuffer{}
+	logger := New(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(DefaultEncoderConf()),
+			zapcore.AddSync(os.Stdout),
+			DebugLevel,
+		),
+		ErrorOutput(errBuf),
+	)
+
+	require.NoError(t, logger.Close(), "Unexpected error closing logger.")
+	assert.False(t, errBuf.closed, "Expected a caller-supplied error output to be left open.")
+	_, err := os.Stdout.Stat()
+	assert.NoError(t, err, "Expected stdout to be left open.")
+}
+
+type constantClock time.Time
+
+func (c constantClock) Now() time.Time { return time.Time(c) }
+func (c constantClock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+func TestLoggerWithClock(t *testing.T) {
+	date := time.Date(2077, 1, 23, 10, 15, 13, 441, time.UTC)
+	withLogger(t, DebugLevel, opts(WithClock(constantClock(date))), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.Info("")
+		require.Equal(t, 1, logs.Len(), "Expected only one log entry to be written.")
+		assert.Equal(t, date, logs.All()[0].Entry.Time, "Unexpected entry time.")
+	})
+}
+
+func TestIncreaseLevel(t *testing.T) {
+	errorOut := &testBuffer{}
+	withLogger(t, WarnLevel, opts(ErrorOutput(errorOut)), func(logger *Logger, logs *observer.ObservedLogs) {
+		logger.Warn("original warn log")
+
+		errorLogger := logger.WithOptions(IncreaseLevel(ErrorLevel))
+		errorLogger.Debug("ignored debug log")
+		errorLogger.Warn("ignored warn log")
+		errorLogger.Error("increase level error log")
+
+		withFields := errorLogger.With(String("k", "v"))
+		withFields.Debug("ignored debug log with fields")
+		withFields.Warn("ignored warn log with fields")
+		withFields.Error("increase level error log with fields")
+
+		assert.Equal(t, []observer.LoggedEntry{
+			{Entry: zapcore.Entry{Level: WarnLevel, Message: "original warn log"}, Context: []Field{}},
+			{Entry: zapcore.Entry{Level: ErrorLevel, Message: "increase level error log"}, Context: []Field{}},
+			{Entry: zapcore.Entry{Level: ErrorLevel, Message: "increase level error log with fields"}, Context: []Field{String("k", "v")}},
+		}, logs.AllUntimed(), "unexpected logs")
+
+		assert.Empty(t, errorOut.String(), "expect no error output")
+		assert.Equal(t, WarnLevel, logger.Level(), "Expected parent level to be unchanged.")
+	})
+}
+
+func TestIncreaseLevelTryDecrease(t *testing.T) {
+	errorOut := &testBuffer{}
+	withLogger(t, WarnLevel, opts(ErrorOutput(errorOut)), func(logger *Logger, logs *observer.ObservedLogs) {
+		debugLogger := logger.WithOptions(IncreaseLevel(DebugLevel))
+		debugLogger.Debug("ignored debug log")
+		debugLogger.Warn("original warn log")
+
+		assert.Equal(t, []observer.LoggedEntry{
+			{Entry: zapcore.Entry{Level: WarnLevel, Message: "original warn log"}, Context: []Field{}},
+		}, logs.AllUntimed(), "unexpected logs")
+
+		assert.Equal(t,
+			`failed to IncreaseLevel: invalid increase level, as level "info" is allowed by increased level, but not by existing core`,
+			strings.TrimSpace(errorOut.String()),
+			"unexpected error output",
+		)
+	})
+}
+
 func TestLoggerConcurrent(t *testing.T) {
 	withLogger(t, DebugLevel, nil, func(logger *Logger, logs *observer.ObservedLogs) {
 		child := logger.With(String("foo", "bar"))
@@ -20,7 +20,11 @@
 
 package zap
 
-import "github.com/templexxx/zap/zapcore"
+import (
+	"fmt"
+
+	"github.com/templexxx/zap/zapcore"
+)
 
 // An Option configures a Logger.
 type Option interface {
@@ -60,3 +64,98 @@ func Fields(fs ...Field) Option {
 		log.core = log.core.With(fs)
 	})
 }
+
+// AddCaller configures the Logger to annotate each message with the filename
+// and line number of zap's caller. See also WithCaller.
+func AddCaller() Option {
+	return WithCaller(true)
+}
+
+// WithCaller configures the Logger to annotate each message with the filename
+// and line number of zap's caller, or not, depending on the value of enabled.
+// This is a generalized form of AddCaller.
+func WithCaller(enabled bool) Option {
+	return optionFunc(func(log *Logger) {
+		log.addCaller = enabled
+	})
+}
+
+// AddCallerSkip increases the number of callers skipped by caller annotation
+// (as enabled by the AddCaller option). When building wrappers around the
+// Logger, supplying this Option prevents zap from always reporting the
+// wrapper code as the caller.
+func AddCallerSkip(skip int) Option {
+	return optionFunc(func(log *Logger) {
+		log.callerSkip += skip
+	})
+}
+
+// AddStacktrace configures the Logger to record a stack trace for all messages at
+// or above a given level.
+func AddStacktrace(lvl zapcore.LevelEnabler) Option {
+	return optionFunc(func(log *Logger) {
+		log.addStack = lvl
+	})
+}
+
+// Development puts the logger in development mode, which makes DPanic-level
+// logs panic instead of simply logging an error.
+func Development() Option {
+	return optionFunc(func(log *Logger) {
+		log.development = true
+	})
+}
+
+// ErrorOutput sets the destination for errors generated by the Logger. Note
+// that this option only affects internal errors, such as failing to write an
+// entry to the underlying WriteSyncer or failing to resolve the caller.
+//
+// The supplied WriteSyncer must be safe for concurrent use. The
+// zapcore.Lock function is the simplest way to protect files with a mutex.
+func ErrorOutput(w zapcore.WriteSyncer) Option {
+	return optionFunc(func(log *Logger) {
+		log.errorOutput = w
+		log.ownsErrorOutput = false
+	})
+}
+
+// ownedErrorOutput is like ErrorOutput, but makes Logger.Close close w.
+func ownedErrorOutput(w zapcore.WriteSyncer) Option {
+	return optionFunc(func(log *Logger) {
+		log.errorOutput = w
+		log.ownsErrorOutput = true
+	})
+}
+
+// ContextExtractors registers functions which pull fields out of the
+// context.Context passed to the Logger's context-aware methods, such as
+// InfoCtx. Repeated use of ContextExtractors is additive.
+func ContextExtractors(extractors ...ContextExtractor) Option {
+	return optionFunc(func(log *Logger) {
+		n := len(log.extractors)
+		log.extractors = append(log.extractors[:n:n], extractors...)
+	})
+}
+
+// IncreaseLevel increase the level of the logger. It has no effect if
+// the passed in level tries to decrease the level of the logger; the
+// rejection is reported to the Logger's ErrorOutput instead.
+func IncreaseLevel(lvl zapcore.LevelEnabler) Option {
+	return optionFunc(func(log *Logger) {
+		core, err := zapcore.NewIncreaseLevelCore(log.core, lvl)
+		if err != nil {
+			fmt.Fprintf(log.errorOutput, "failed to IncreaseLevel: %v\n", err)
+		} else {
+			log.core = core
+		}
+	})
+}
+
+// WithClock specifies the clock used by the logger to determine the current
+// time for logged entries. Defaults to the system clock with time.Now. To
+// rotate output files by the same clock, see Config.Clock.
+func WithClock(clock zapcore.Clock) Option {
+	return optionFunc(func(log *Logger) {
+		log.clock = clock
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zap
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/templexxx/zap/zapcore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reopenCore reports each ReOpen call on a channel.
+type reopenCore struct {
+	zapcore.Core
+	reopened chan struct{}
+	err      error
+}
+
+func (c *reopenCore) ReOpen() error {
+	c.reopened <- struct{}{}
+	return c.err
+}
+
+func TestReOpenOnSignal(t *testing.T) {
+	ok := &reopenCore{Core: zapcore.NewNopCore(), reopened: make(chan struct{}, 1)}
+	failing := &reopenCore{Core: zapcore.NewNopCore(), reopened: make(chan struct{}, 1), err: errors.New("failed")}
+	errs := make(chan error, 1)
+	stop := ReOpenOnSignal([]*Logger{New(ok), New(failing)}, func(err error) { errs <- err })
+	defer stop()
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err, "Failed to find own process.")
+	require.NoError(t, p.Signal(syscall.SIGHUP), "Failed to send SIGHUP.")
+
+	for _, c := range []*reopenCore{ok, failing} {
+		select {
+		case <-c.reopened:
+		case <-time.After(time.Second):
+			t.Fatal("Expected SIGHUP to reopen every logger.")
+		}
+	}
+	select {
+	case err := <-errs:
+		assert.Equal(t, "failed", err.Error(), "Unexpected error reported.")
+	case <-time.After(time.Second):
+		t.Fatal("Expected ReOpen errors to be reported.")
+	}
+
+	stop()
+	assert.NotPanics(t, stop, "Expected stopping again to be a no-op.")
+}
+
+func TestLoggerReOpenErrorOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zap-reopen")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	cfg := DefaultConfig()
+	cfg.OutputPath = filepath.Join(dir, "app.log")
+	cfg.ErrorOutputPath = filepath.Join(dir, "errors.log")
+	logger, err := cfg.Build()
+	require.NoError(t, err, "Unexpected error constructing logger.")
+	defer logger.Close()
+
+	logger.errorOutput.Write([]byte("before\n"))
+	rotated := filepath.Join(dir, "errors.log.1")
+	require.NoError(t, os.Rename(cfg.ErrorOutputPath, rotated), "Failed to rotate the error output.")
+	require.NoError(t, logger.ReOpen(), "Unexpected error reopening.")
+	logger.errorOutput.Write([]byte("after\n"))
+
+	for path, want := range map[string]string{rotated: "before\n", cfg.ErrorOutputPath: "after\n"} {
+		contents, err := ioutil.ReadFile(path)
+		require.NoError(t, err, "Failed to read %s.", path)
+		assert.Equal(t, want, string(contents), "Unexpected contents of %s.", path)
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/templexxx/zap/buffer"
+
+	"go.uber.org/atomic"
+	"go.uber.org/multierr"
+)
+
+const _defaultAsyncQueueSize = 1024
+
+// An OverflowPolicy decides what an AsyncCore does with an entry when its
+// queue is full.
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock makes the caller wait until the queue has room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being logged.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued entry to make room.
+	OverflowDropOldest
+)
+
+// AsyncOption configures an AsyncCore.
+type AsyncOption interface {
+	apply(*asyncWriter)
+}
+
+type asyncOptionFunc func(*asyncWriter)
+
+func (f asyncOptionFunc) apply(w *asyncWriter) {
+	f(w)
+}
+
+// AsyncQueueSize sets the number of encoded entries that may wait for the
+// background writer. The default is 1024.
+func AsyncQueueSize(n int) AsyncOption {
+	return asyncOptionFunc(func(w *asyncWriter) {
+		if n > 0 {
+			w.size = n
+		}
+	})
+}
+
+// AsyncOverflow sets the policy applied when the queue is full. The default
+// is OverflowBlock.
+func AsyncOverflow(p OverflowPolicy) AsyncOption {
+	return asyncOptionFunc(func(w *asyncWriter) {
+		w.policy = p
+	})
+}
+
+// AsyncSpill makes entries which don't fit in the queue get written
+// synchronously to ws (typically a file on local disk) instead, overriding
+// the overflow policy.
+func AsyncSpill(ws WriteSyncer) AsyncOption {
+	return asyncOptionFunc(func(w *asyncWriter) {
+		w.spill = Lock(ws)
+	})
+}
+
+// AsyncErrorHandler makes the background goroutine call f with the error of
+// each failed write, as soon as it happens.
+func AsyncErrorHandler(f func(error)) AsyncOption {
+	return asyncOptionFunc(func(w *asyncWriter) {
+		w.onError = f
+	})
+}
+
+// AsyncCore is a Core which encodes entries on the caller's goroutine, and
+// leaves writing them to a background goroutine fed through a bounded queue.
+//
+// Errors from the background writes are passed to the AsyncErrorHandler, if
+// any, and summarized by the next call to Sync or Close: the number of failed
+// writes and the last error.
+type AsyncCore struct {
+	LevelEnabler
+	enc Encoder
+	w   *asyncWriter
+}
+
+// NewAsyncCore creates an AsyncCore that writes logs to a WriteSyncer.
+func NewAsyncCore(enc Encoder, ws WriteSyncer, enab LevelEnabler, opts ...AsyncOption) *AsyncCore {
+	w := &asyncWriter{
+		out:  Lock(ws),
+		size: _defaultAsyncQueueSize,
+	}
+	for _, opt := range opts {
+		opt.apply(w)
+	}
+	w.queue = make([]*buffer.Buffer, w.size)
+	w.cond = sync.NewCond(&w.mu)
+	w.stopped = make(chan struct{})
+	go w.run()
+
+	return &AsyncCore{
+		LevelEnabler: enab,
+		enc:          enc,
+		w:            w,
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was full.
+func (c *AsyncCore) Dropped() uint64 {
+	return c.w.dropped.Load()
+}
+
+// Failed returns the number of entries whose background write failed.
+func (c *AsyncCore) Failed() uint64 {
+	return c.w.failed.Load()
+}
+
+// Spilled returns the number of entries written to the spill WriteSyncer
+// because the queue was full.
+func (c *AsyncCore) Spilled() uint64 {
+	return c.w.spilled.Load()
+}
+
+func (c *AsyncCore) With(fields []Field) Core {
+	clone := &AsyncCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          c.enc.Clone(),
+		w:            c.w,
+	}
+	addFields(clone.enc, fields)
+	return clone
+}
+
+func (c *AsyncCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *AsyncCore) Write(ent Entry, fields []Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	if err := c.w.enqueue(buf); err != nil {
+		return err
+	}
+	if ent.Level > ErrorLevel {
+		// Since we may be crashing the program, drain the queue and sync the
+		// output. Ignore Sync errors, as ioCore does.
+		c.Sync()
+	}
+	return nil
+}
+
+// Sync waits until every entry queued before the call has been written, then
+// flushes the output.
+func (c *AsyncCore) Sync() error {
+	return c.w.sync()
+}
+
+func (c *AsyncCore) ReOpen() error {
+	err := c.w.out.ReOpen()
+	if c.w.spill != nil {
+		err = multierr.Append(err, c.w.spill.ReOpen())
+	}
+	return err
+}
+
+// Close writes the queued entries, stops the background goroutine, and
+// closes the output and spill WriteSyncers. Entries written after Close are
+// discarded with an error.
+func (c *AsyncCore) Close() error {
+	return c.w.close()
+}
+
+type asyncWriter struct {
+	out     WriteSyncer
+	spill   WriteSyncer
+	policy  OverflowPolicy
+	size    int
+	onError func(error)
+
+	dropped atomic.Uint64
+	spilled atomic.Uint64
+	failed  atomic.Uint64
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue is a ring buffer holding count entries starting at head.
+	queue []*buffer.Buffer
+	head  int
+	count int
+	// queued counts entries ever accepted into the queue, and done counts
+	// those written or dropped since; Sync waits for done to catch up.
+	queued uint64
+	done   uint64
+	// errCount and lastErr describe the writes failed since the last Sync.
+	errCount uint64
+	lastErr  error
+	// closed stops the background goroutine once the queue is empty, which
+	// then closes stopped.
+	closed  bool
+	stopped chan struct{}
+}
+
+func (w *asyncWriter) enqueue(buf *buffer.Buffer) error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		buf.Free()
+		return errClosed
+	}
+	if w.count == len(w.queue) {
+		switch {
+		case w.spill != nil:
+			w.mu.Unlock()
+			_, err := w.spill.Write(buf.Bytes())
+			buf.Free()
+			w.spilled.Inc()
+			return err
+		case w.policy == OverflowDropNewest:
+			w.mu.Unlock()
+			buf.Free()
+			w.dropped.Inc()
+			return nil
+		case w.policy == OverflowDropOldest:
+			w.pop().Free()
+			w.done++
+			w.dropped.Inc()
+		default:
+			for w.count == len(w.queue) && !w.closed {
+				w.cond.Wait()
+			}
+			if w.closed {
+				w.mu.Unlock()
+				buf.Free()
+				return errClosed
+			}
+		}
+	}
+	w.queue[(w.head+w.count)%len(w.queue)] = buf
+	w.count++
+	w.queued++
+	w.mu.Unlock()
+	w.cond.Broadcast()
+	return nil
+}
+
+// pop removes the oldest queued entry. It must be called with mu held.
+func (w *asyncWriter) pop() *buffer.Buffer {
+	buf := w.queue[w.head]
+	w.queue[w.head] = nil
+	w.head = (w.head + 1) % len(w.queue)
+	w.count--
+	return buf
+}
+
+func (w *asyncWriter) run() {
+	w.mu.Lock()
+	for {
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			close(w.stopped)
+			return
+		}
+		buf := w.pop()
+		w.mu.Unlock()
+		// Wake producers blocked on a full queue.
+		w.cond.Broadcast()
+
+		_, err := w.out.Write(buf.Bytes())
+		buf.Free()
+		if err != nil {
+			w.failed.Inc()
+			if w.onError != nil {
+				w.onError(err)
+			}
+		}
+
+		w.mu.Lock()
+		if err != nil {
+			w.errCount++
+			w.lastErr = err
+		}
+		w.done++
+		w.cond.Broadcast()
+	}
+}
+
+func (w *asyncWriter) sync() error {
+	w.mu.Lock()
+	for target := w.queued; w.done < target; {
+		w.cond.Wait()
+	}
+	err := w.takeErr()
+	w.mu.Unlock()
+
+	err = multierr.Append(err, w.out.Sync())
+	if w.spill != nil {
+		err = multierr.Append(err, w.spill.Sync())
+	}
+	return err
+}
+
+func (w *asyncWriter) close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	w.cond.Broadcast()
+	<-w.stopped
+
+	w.mu.Lock()
+	err := w.takeErr()
+	w.mu.Unlock()
+
+	err = multierr.Append(err, w.out.Close())
+	if w.spill != nil {
+		err = multierr.Append(err, w.spill.Close())
+	}
+	return err
+}
+
+// takeErr summarizes and forgets the writes failed since the last call. It
+// must be called with mu held.
+func (w *asyncWriter) takeErr() error {
+	n, err := w.errCount, w.lastErr
+	w.errCount, w.lastErr = 0, nil
+	if n > 1 {
+		return fmt.Errorf("%d background writes failed, the last with: %v", n, err)
+	}
+	return err
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/templexxx/zap/zapcore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter is a WriteSyncer safe for concurrent use whose writes can be
+// held up until the gate is closed.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	err     error
+	gate    chan struct{}
+	started chan struct{}
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{
+		gate:    make(chan struct{}),
+		started: make(chan struct{}, 1),
+	}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	select {
+	case w.started <- struct{}{}:
+	default:
+	}
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) Messages() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	return strings.Fields(w.buf.String())
+}
+
+func (w *gatedWriter) Sync() error   { return nil }
+func (w *gatedWriter) ReOpen() error { return nil }
+func (w *gatedWriter) Close() error  { return nil }
+
+func newAsyncTestCore(ws WriteSyncer, opts ...AsyncOption) *AsyncCore {
+	enc := NewJSONEncoder(EncoderConfig{MessageKey: "msg"})
+	return NewAsyncCore(enc, ws, InfoLevel, opts...)
+}
+
+func writeAsync(t *testing.T, core Core, lvl Level, msgs ...string) {
+	for _, msg := range msgs {
+		ce := core.Check(Entry{Level: lvl, Message: msg}, nil)
+		require.NotNil(t, ce, "Expected entry at %v to be enabled.", lvl)
+		ce.Write()
+	}
+}
+
+func TestAsyncCoreWrite(t *testing.T) {
+	ws := newGatedWriter()
+	close(ws.gate)
+	core := newAsyncTestCore(ws)
+
+	assert.Nil(t, core.Check(Entry{Level: DebugLevel}, nil), "Expected disabled entries to be dropped.")
+	writeAsync(t, core, InfoLevel, "a", "b")
+	writeAsync(t, core.With([]Field{makeInt64Field("k", 1)}), InfoLevel, "c")
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+
+	assert.Equal(t, []string{
+		`{"msg":"a"}`,
+		`{"msg":"b"}`,
+		`{"msg":"c","k":1}`,
+	}, ws.Messages(), "Unexpected output after Sync.")
+	assert.Zero(t, core.Dropped(), "Unexpected dropped entries.")
+}
+
+func TestAsyncCoreOverflow(t *testing.T) {
+	tests := []struct {
+		desc      string
+		opt       func(spill WriteSyncer) AsyncOption
+		want      []string
+		wantSpill []string
+		dropped   uint64
+		spilled   uint64
+	}{
+		{
+			desc:    "drop newest",
+			opt:     func(WriteSyncer) AsyncOption { return AsyncOverflow(OverflowDropNewest) },
+			want:    []string{`{"msg":"0"}`, `{"msg":"1"}`, `{"msg":"2"}`},
+			dropped: 1,
+		},
+		{
+			desc:    "drop oldest",
+			opt:     func(WriteSyncer) AsyncOption { return AsyncOverflow(OverflowDropOldest) },
+			want:    []string{`{"msg":"0"}`, `{"msg":"2"}`, `{"msg":"3"}`},
+			dropped: 1,
+		},
+		{
+			desc:      "spill",
+			opt:       AsyncSpill,
+			want:      []string{`{"msg":"0"}`, `{"msg":"1"}`, `{"msg":"2"}`},
+			wantSpill: []string{`{"msg":"3"}`},
+			spilled:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ws, spill := newGatedWriter(), newGatedWriter()
+			close(spill.gate)
+			core := newAsyncTestCore(ws, AsyncQueueSize(2), tt.opt(spill))
+
+			// Hold the first entry in the background writer, then fill the
+			// queue and overflow it by one.
+			writeAsync(t, core, InfoLevel, "0")
+			<-ws.started
+			writeAsync(t, core, InfoLevel, "1", "2", "3")
+
+			close(ws.gate)
+			require.NoError(t, core.Sync(), "Unexpected error syncing.")
+			assert.Equal(t, tt.want, ws.Messages(), "Unexpected output.")
+			assert.Equal(t, tt.wantSpill, spill.Messages(), "Unexpected spilled output.")
+			assert.Equal(t, tt.dropped, core.Dropped(), "Unexpected dropped count.")
+			assert.Equal(t, tt.spilled, core.Spilled(), "Unexpected spilled count.")
+		})
+	}
+}
+
+func TestAsyncCoreOverflowBlock(t *testing.T) {
+	ws := newGatedWriter()
+	core := newAsyncTestCore(ws, AsyncQueueSize(1))
+
+	writeAsync(t, core, InfoLevel, "0")
+	<-ws.started
+	writeAsync(t, core, InfoLevel, "1")
+
+	wrote := make(chan struct{})
+	go func() {
+		core.Write(Entry{Level: InfoLevel, Message: "2"}, nil)
+		close(wrote)
+	}()
+	select {
+	case <-wrote:
+		t.Fatal("Expected Write to block while the queue is full.")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(ws.gate)
+	<-wrote
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, []string{`{"msg":"0"}`, `{"msg":"1"}`, `{"msg":"2"}`}, ws.Messages(), "Unexpected output.")
+	assert.Zero(t, core.Dropped(), "Expected blocking policy not to drop entries.")
+}
+
+func TestAsyncCoreDrainsAboveError(t *testing.T) {
+	ws := newGatedWriter()
+	close(ws.gate)
+	core := newAsyncTestCore(ws)
+
+	writeAsync(t, core, InfoLevel, "info")
+	core.Write(Entry{Level: DPanicLevel, Message: "dpanic"}, nil)
+	assert.Equal(t, []string{`{"msg":"info"}`, `{"msg":"dpanic"}`}, ws.Messages(), "Expected queue to be drained.")
+}
+
+func TestAsyncCoreWriteErrors(t *testing.T) {
+	ws := newGatedWriter()
+	ws.err = errors.New("fail")
+	close(ws.gate)
+	core := newAsyncTestCore(ws)
+
+	writeAsync(t, core, InfoLevel, "a")
+	assert.Equal(t, "fail", core.Sync().Error(), "Expected a background write error from Sync.")
+	writeAsync(t, core, InfoLevel, "b", "c")
+	assert.Equal(t, "2 background writes failed, the last with: fail", core.Sync().Error(), "Expected background write errors to be summarized.")
+	assert.NoError(t, core.Sync(), "Expected errors to be reported only once.")
+	assert.Equal(t, uint64(3), core.Failed(), "Unexpected number of failed writes.")
+}
+
+func TestAsyncCoreErrorHandler(t *testing.T) {
+	ws := newGatedWriter()
+	ws.err = errors.New("fail")
+	close(ws.gate)
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	core := newAsyncTestCore(ws, AsyncErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+
+	writeAsync(t, core, InfoLevel, "a", "b")
+	core.Sync()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []error{ws.err, ws.err}, errs, "Expected each failed write to be reported.")
+}
+
+func TestAsyncCoreClose(t *testing.T) {
+	ws := newGatedWriter()
+	core := newAsyncTestCore(ws)
+	writeAsync(t, core, InfoLevel, "a", "b")
+
+	closed := make(chan error)
+	go func() { closed <- core.Close() }()
+	close(ws.gate)
+	require.NoError(t, <-closed, "Unexpected error closing.")
+	assert.Equal(t, []string{`{"msg":"a"}`, `{"msg":"b"}`}, ws.Messages(), "Expected Close to write queued entries.")
+
+	ce := core.Check(Entry{Level: InfoLevel, Message: "c"}, nil)
+	require.NotNil(t, ce, "Expected entry to be enabled.")
+	assert.Error(t, core.Write(ce.Entry, nil), "Expected writes after Close to fail.")
+	assert.NoError(t, core.Close(), "Expected closing again to be a no-op.")
+}
@@ -45,6 +45,10 @@ type Core interface {
 
 	// ReOpen open log file again for log rotation
 	ReOpen() error
+	// Close flushes buffered logs, stops background work and closes the
+	// output. It's safe to call more than once; Cores created by With share
+	// their parent's output, so closing any of them closes them all.
+	Close() error
 }
 
 type nopCore struct{}
@@ -57,6 +61,7 @@ func (nopCore) Check(_ Entry, ce *CheckedEntry) *CheckedEntry { return ce }
 func (nopCore) Write(Entry, []Field) error                    { return nil }
 func (nopCore) Sync() error                                   { return nil }
 func (nopCore) ReOpen() error                                 { return nil }
+func (nopCore) Close() error                                  { return nil }
 
 // NewCore creates a Core that writes logs to a WriteSyncer.
 func NewCore(enc Encoder, ws WriteSyncer, enab LevelEnabler) Core {
@@ -112,6 +117,10 @@ func (c *ioCore) ReOpen() error {
 	return c.out.ReOpen()
 }
 
+func (c *ioCore) Close() error {
+	return c.out.Close()
+}
+
 func (c *ioCore) clone() *ioCore {
 	return &ioCore{
 		LevelEnabler: c.LevelEnabler,
@@ -119,3 +128,37 @@ func (c *ioCore) clone() *ioCore {
 		out:          c.out,
 	}
 }
+
+// A writeInterceptor is a Core wrapping another, whose Write does more than
+// pass entries on, such as holding them back.
+type writeInterceptor interface {
+	Core
+	// writeTo handles a Write, passing entries on to out.
+	writeTo(out multiCore, ent Entry, fields []Field) error
+}
+
+// interceptedCore is added to a CheckedEntry in place of a writeInterceptor,
+// so that entries are passed on to the Cores which the wrapped Core's Check
+// chose, rather than to every Core it wraps.
+type interceptedCore struct {
+	writeInterceptor
+	out multiCore
+}
+
+func (c interceptedCore) Write(ent Entry, fields []Field) error {
+	return c.writeTo(c.out, ent, fields)
+}
+
+// checkIntercepted adds c to ce if inner's Check chooses any Cores for ent.
+// If always is set, c is added regardless.
+func checkIntercepted(c writeInterceptor, inner Core, ent Entry, ce *CheckedEntry, always bool) *CheckedEntry {
+	var out multiCore
+	if in := inner.Check(ent, nil); in != nil {
+		out = append(out, in.cores...)
+		putCheckedEntry(in)
+	}
+	if len(out) == 0 && !always {
+		return ce
+	}
+	return ce.AddCore(ent, interceptedCore{c, out})
+}
@@ -35,6 +35,14 @@ func makeInt64Field(key string, val int) Field {
 	return Field{Type: Int64Type, Integer: int64(val), Key: key}
 }
 
+// checkAndWrite writes ent and fields through core if its Check method lets
+// the entry through.
+func checkAndWrite(core Core, ent Entry, fields ...Field) {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
 func TestIOCore(t *testing.T) {
 	temp, err := ioutil.TempFile("", "zapcore-test-iocore")
 	require.NoError(t, err, "Failed to create temp file.")
@@ -0,0 +1,266 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Keys of the fields added to the summary entries written by a dedupe Core.
+const (
+	DedupeRepeatedKey  = "repeated"
+	DedupeFirstSeenKey = "firstSeen"
+	DedupeLastSeenKey  = "lastSeen"
+)
+
+const _defaultDedupeMaxKeys = 10000
+
+// DedupeOption configures a dedupe Core.
+type DedupeOption interface {
+	apply(*dedupeState)
+}
+
+type dedupeOptionFunc func(*dedupeState)
+
+func (f dedupeOptionFunc) apply(s *dedupeState) {
+	f(s)
+}
+
+// DedupeMaxKeys bounds the number of distinct entries with an open window.
+// Once the bound is reached, further distinct entries are written without
+// being tracked until some windows end. The default is 10000.
+func DedupeMaxKeys(n int) DedupeOption {
+	return dedupeOptionFunc(func(s *dedupeState) {
+		if n > 0 {
+			s.maxKeys = n
+		}
+	})
+}
+
+type dedupeState struct {
+	window  time.Duration
+	maxKeys int
+
+	mu      sync.Mutex
+	pending map[string]*dedupeEntry
+
+	// stop ends the goroutine ending expired windows, which then closes
+	// stopped.
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// dedupeEntry tracks the duplicates of an entry seen within a window.
+type dedupeEntry struct {
+	core        Core
+	ent         Entry
+	fields      []Field
+	repeated    uint64
+	first, last time.Time
+	// expires is when the window ends, by the system clock.
+	expires time.Time
+}
+
+type dedupeCore struct {
+	Core
+	state *dedupeState
+	// context identifies the fields added with With, since entries logged
+	// with different context aren't identical.
+	context string
+}
+
+// NewDedupeCore wraps a Core and suppresses identical entries within a
+// window. Entries are identical if they have the same level, logger name,
+// message and field values, including fields added with With.
+//
+// The first occurrence of an entry is written immediately and starts a
+// window. If any duplicates were suppressed when the window ends, a summary
+// entry is written with the original fields plus the number of duplicates
+// and the times the entry was first and last seen. The fields are evaluated
+// when the window starts, like in a flight recorder. A background goroutine
+// ends expired windows every half window, so a window may last up to half
+// as long again. Sync and Close end all open windows early, writing their
+// summaries, and Close stops the goroutine.
+//
+// Entries above ErrorLevel are never suppressed, and nothing is suppressed
+// if window isn't positive.
+func NewDedupeCore(core Core, window time.Duration, opts ...DedupeOption) Core {
+	s := &dedupeState{
+		window:  window,
+		maxKeys: _defaultDedupeMaxKeys,
+		pending: make(map[string]*dedupeEntry),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+	if window > 0 {
+		go s.sweepEvery(window / 2)
+	} else {
+		close(s.stopped)
+	}
+	return &dedupeCore{Core: core, state: s}
+}
+
+func (c *dedupeCore) With(fields []Field) Core {
+	return &dedupeCore{
+		Core:    c.Core.With(fields),
+		state:   c.state,
+		context: c.context + fieldsKey(fields),
+	}
+}
+
+func (c *dedupeCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return checkIntercepted(c, c.Core, ent, ce, false)
+}
+
+func (c *dedupeCore) Write(ent Entry, fields []Field) error {
+	return c.writeTo(multiCore{c.Core}, ent, fields)
+}
+
+func (c *dedupeCore) writeTo(out multiCore, ent Entry, fields []Field) error {
+	if ent.Level > ErrorLevel || c.state.window <= 0 {
+		return out.Write(ent, fields)
+	}
+
+	key := fmt.Sprintf("%d\x00%s\x00%s\x00%s%s", ent.Level, ent.LoggerName, ent.Message, c.context, fieldsKey(fields))
+	s := c.state
+	s.mu.Lock()
+	if e, ok := s.pending[key]; ok {
+		e.repeated++
+		e.last = ent.Time
+		s.mu.Unlock()
+		return nil
+	}
+	if len(s.pending) < s.maxKeys {
+		s.pending[key] = &dedupeEntry{
+			core:    out,
+			ent:     ent,
+			fields:  freezeFields(fields),
+			first:   ent.Time,
+			last:    ent.Time,
+			expires: time.Now().Add(s.window),
+		}
+	}
+	s.mu.Unlock()
+
+	return out.Write(ent, fields)
+}
+
+func (c *dedupeCore) Sync() error {
+	c.state.flushAll()
+	return c.Core.Sync()
+}
+
+func (c *dedupeCore) Close() error {
+	s := c.state
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+	})
+	s.flushAll()
+	return c.Core.Close()
+}
+
+func (s *dedupeState) sweepEvery(d time.Duration) {
+	defer close(s.stopped)
+	if d <= 0 {
+		d = s.window
+	}
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.flushExpired(now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flushExpired ends the windows which expired by now.
+func (s *dedupeState) flushExpired(now time.Time) {
+	var expired []*dedupeEntry
+	s.mu.Lock()
+	for key, e := range s.pending {
+		if !now.Before(e.expires) {
+			expired = append(expired, e)
+			delete(s.pending, key)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, e := range expired {
+		e.writeSummary()
+	}
+}
+
+func (s *dedupeState) flushAll() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]*dedupeEntry)
+	s.mu.Unlock()
+
+	for _, e := range pending {
+		e.writeSummary()
+	}
+}
+
+func (e *dedupeEntry) writeSummary() {
+	if e.repeated == 0 {
+		return
+	}
+	ent := e.ent
+	ent.Time = e.last
+	fields := append(e.fields,
+		Field{Key: DedupeRepeatedKey, Type: Uint64Type, Integer: int64(e.repeated)},
+		timeField(DedupeFirstSeenKey, e.first),
+		timeField(DedupeLastSeenKey, e.last),
+	)
+	// Like the sampler, there's nowhere to report errors from here.
+	e.core.Write(ent, fields)
+}
+
+func timeField(key string, t time.Time) Field {
+	return Field{Key: key, Type: TimeType, Integer: t.UnixNano(), Interface: t.Location()}
+}
+
+// fieldsKey returns a string which is equal for fields with equal keys and
+// values.
+func fieldsKey(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	enc := NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	// fmt sorts map keys, so the result doesn't depend on field order.
+	return fmt.Sprint(enc.Fields)
+}
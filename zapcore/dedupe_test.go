@@ -0,0 +1,176 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeCore(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, time.Hour)
+	defer core.Close()
+
+	t0 := time.Unix(1, 0)
+	for i := 0; i < 5; i++ {
+		ent := Entry{Level: ErrorLevel, Message: "boom", Time: t0.Add(time.Duration(i) * time.Second)}
+		checkAndWrite(core, ent, makeInt64Field("k", 1))
+	}
+	// Entries differing in level, message, field values or context aren't
+	// identical.
+	checkAndWrite(core, Entry{Level: WarnLevel, Message: "boom", Time: t0}, makeInt64Field("k", 1))
+	checkAndWrite(core, Entry{Level: ErrorLevel, Message: "bang", Time: t0}, makeInt64Field("k", 1))
+	checkAndWrite(core, Entry{Level: ErrorLevel, Message: "boom", Time: t0}, makeInt64Field("k", 2))
+	checkAndWrite(core.With([]Field{makeInt64Field("c", 1)}), Entry{Level: ErrorLevel, Message: "boom", Time: t0}, makeInt64Field("k", 1))
+	assert.Equal(t, 5, logs.Len(), "Expected duplicates to be suppressed.")
+
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	summaries := logs.AllUntimed()[5:]
+	require.Equal(t, 1, len(summaries), "Expected a single summary entry.")
+	assert.Equal(t, observer.LoggedEntry{
+		Entry: Entry{Level: ErrorLevel, Message: "boom"},
+		Context: []Field{
+			makeInt64Field("k", 1),
+			{Key: DedupeRepeatedKey, Type: Uint64Type, Integer: 4},
+			{Key: DedupeFirstSeenKey, Type: TimeType, Integer: t0.UnixNano(), Interface: t0.Location()},
+			{Key: DedupeLastSeenKey, Type: TimeType, Integer: t0.Add(4 * time.Second).UnixNano(), Interface: t0.Location()},
+		},
+	}, summaries[0], "Unexpected summary entry.")
+	assert.Equal(t, t0.Add(4*time.Second), logs.All()[5].Time, "Expected summary to carry the last timestamp.")
+
+	// Sync ends the window, so the next occurrence is written again.
+	checkAndWrite(core, Entry{Level: ErrorLevel, Message: "boom", Time: t0}, makeInt64Field("k", 1))
+	assert.Equal(t, 7, logs.Len(), "Expected a new window after Sync.")
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, 7, logs.Len(), "Expected no summary without duplicates.")
+}
+
+func TestDedupeCoreWindow(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, 10*time.Millisecond)
+	defer core.Close()
+
+	for i := 0; i < 3; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "tick", Time: time.Now()})
+	}
+	for i := 0; i < 100 && logs.Len() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 2, logs.Len(), "Expected a summary when the window ends.")
+	summary := logs.All()[1]
+	assert.Equal(t, "tick", summary.Message, "Unexpected summary message.")
+	assert.Equal(t, uint64(2), summary.ContextMap()[DedupeRepeatedKey], "Unexpected repeat count.")
+
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "tick", Time: time.Now()})
+	assert.Equal(t, 3, logs.Len(), "Expected a new window after the summary.")
+}
+
+func TestDedupeCoreSkipsSevereEntries(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, time.Hour)
+	defer core.Close()
+
+	for i := 0; i < 3; i++ {
+		core.Write(Entry{Level: DPanicLevel, Message: "dpanic"}, nil)
+	}
+	assert.Equal(t, 3, logs.Len(), "Expected entries above ErrorLevel to pass through.")
+}
+
+func TestDedupeCoreLevel(t *testing.T) {
+	obs, _ := observer.New(WarnLevel)
+	core := NewDedupeCore(obs, time.Hour)
+	defer core.Close()
+
+	assert.Nil(t, core.Check(Entry{Level: InfoLevel}, nil), "Expected disabled entries to be dropped.")
+	assert.NotNil(t, core.Check(Entry{Level: WarnLevel}, nil), "Expected enabled entries to be checked.")
+}
+
+func TestDedupeCoreClose(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, time.Hour)
+	defer core.Close()
+	for i := 0; i < 2; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "dup"})
+	}
+	require.NoError(t, core.Close(), "Unexpected error closing.")
+	assert.Equal(t, 2, logs.Len(), "Expected Close to write pending summaries.")
+}
+
+func TestDedupeCoreRespectsWrappedCheck(t *testing.T) {
+	debug, debugLogs := observer.New(DebugLevel)
+	errs, errLogs := observer.New(ErrorLevel)
+	core := NewDedupeCore(NewTee(debug, errs), time.Hour)
+	defer core.Close()
+
+	for i := 0; i < 2; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "info"})
+	}
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, 2, debugLogs.Len(), "Expected the entry and its summary on the enabled Core.")
+	assert.Equal(t, 0, errLogs.Len(), "Expected nothing on the Core which doesn't log InfoLevel.")
+}
+
+func TestDedupeCoreMaxKeys(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, time.Hour, DedupeMaxKeys(100))
+	defer core.Close()
+
+	for i := 0; i < 1000; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, makeInt64Field("id", i))
+	}
+	assert.Equal(t, 1000, logs.Len(), "Expected every distinct entry to be written.")
+
+	// The first entries are tracked, later ones aren't.
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, makeInt64Field("id", 0))
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, makeInt64Field("id", 999))
+	assert.Equal(t, 1001, logs.Len(), "Expected only untracked entries to be written again.")
+
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, 1, logs.FilterField(Field{Key: DedupeRepeatedKey, Type: Uint64Type, Integer: 1}).Len(), "Expected a summary of the tracked entry.")
+
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, makeInt64Field("id", 999))
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, makeInt64Field("id", 999))
+	assert.Equal(t, 1003, logs.Len(), "Expected entries to be tracked again once windows end.")
+}
+
+func TestDedupeCoreFreezesFields(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewDedupeCore(obs, time.Hour)
+	defer core.Close()
+
+	user := &struct{ Name string }{"alice"}
+	for i := 0; i < 2; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, Field{Key: "user", Type: ReflectType, Interface: user})
+	}
+	user.Name = "bob"
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+
+	require.Equal(t, 2, logs.Len(), "Expected the entry and its summary.")
+	assert.Equal(t, `{"Name":"alice"}`, fmt.Sprintf("%s", logs.All()[1].ContextMap()["user"]), "Expected the summary to keep the logged value.")
+}
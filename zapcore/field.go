@@ -108,10 +108,8 @@ func (f Field) AddTo(enc ObjectEncoder) {
 	var err error
 
 	switch f.Type {
-	case ArrayMarshalerType:
-		err = enc.AddArray(f.Key, f.Interface.(ArrayMarshaler))
-	case ObjectMarshalerType:
-		err = enc.AddObject(f.Key, f.Interface.(ObjectMarshaler))
+	case ArrayMarshalerType, ObjectMarshalerType, ReflectType, StringerType, ErrorType:
+		err = f.addUserValue(enc)
 	case BinaryType:
 		enc.AddBinary(f.Key, f.Interface.([]byte))
 	case BoolType:
@@ -155,14 +153,8 @@ func (f Field) AddTo(enc ObjectEncoder) {
 		enc.AddUint8(f.Key, uint8(f.Integer))
 	case UintptrType:
 		enc.AddUintptr(f.Key, uintptr(f.Integer))
-	case ReflectType:
-		err = enc.AddReflected(f.Key, f.Interface)
 	case NamespaceType:
 		enc.OpenNamespace(f.Key)
-	case StringerType:
-		enc.AddString(f.Key, f.Interface.(fmt.Stringer).String())
-	case ErrorType:
-		encodeError(f.Key, f.Interface.(error), enc)
 	case SkipType:
 		break
 	default:
@@ -174,6 +166,69 @@ func (f Field) AddTo(enc ObjectEncoder) {
 	}
 }
 
+// addUserValue adds a field whose encoding calls user-supplied methods. If
+// one of them panics, the panic is returned as an error, and any partial
+// output is discarded if the encoder supports it.
+func (f Field) addUserValue(enc ObjectEncoder) (err error) {
+	r, canRestore := enc.(encoderRestorer)
+	var m encoderMark
+	if canRestore {
+		m = r.mark()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if canRestore {
+				r.restore(m, f.Key)
+			}
+			err = fmt.Errorf("PANIC=%v", p)
+		}
+	}()
+
+	switch f.Type {
+	case ArrayMarshalerType:
+		return enc.AddArray(f.Key, f.Interface.(ArrayMarshaler))
+	case ObjectMarshalerType:
+		return enc.AddObject(f.Key, f.Interface.(ObjectMarshaler))
+	case ReflectType:
+		return enc.AddReflected(f.Key, f.Interface)
+	case StringerType:
+		enc.AddString(f.Key, f.Interface.(fmt.Stringer).String())
+	case ErrorType:
+		encodeError(f.Key, f.Interface.(error), enc)
+	}
+	return nil
+}
+
+// encoderMark records the state of an encoder before a field is added.
+type encoderMark struct {
+	len            int
+	openNamespaces int
+}
+
+// encoderRestorer is implemented by encoders which can discard the partial
+// output of a field whose encoding panicked.
+type encoderRestorer interface {
+	mark() encoderMark
+	restore(m encoderMark, key string)
+}
+
+// stringOf evaluates a Stringer or error field, reporting false if that
+// panics. Callers leave such fields for AddTo to report.
+func stringOf(f Field) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	switch f.Type {
+	case StringerType:
+		return f.Interface.(fmt.Stringer).String(), true
+	case ErrorType:
+		return f.Interface.(error).Error(), true
+	}
+	return "", false
+}
+
 // Equals returns whether two fields are equal. For non-primitive types such as
 // errors, marshalers, or reflect types, it uses reflect.DeepEqual.
 func (f Field) Equals(other Field) bool {
@@ -83,6 +83,40 @@ func TestFieldAddingError(t *testing.T) {
 	}
 }
 
+type panicking struct{ s *string }
+
+func (p panicking) String() string { return *p.s }
+func (p panicking) Error() string  { return *p.s }
+
+func (p panicking) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("partial", "value")
+	enc.OpenNamespace("ns")
+	panic("object")
+}
+
+func (p panicking) MarshalLogArray(enc ArrayEncoder) error {
+	enc.AppendString("partial")
+	panic("array")
+}
+
+func TestFieldAddingPanic(t *testing.T) {
+	tests := []struct {
+		t    FieldType
+		want string
+	}{
+		{ArrayMarshalerType, "PANIC=array"},
+		{ObjectMarshalerType, "PANIC=object"},
+		{StringerType, "PANIC=runtime error: invalid memory address or nil pointer dereference"},
+		{ErrorType, "PANIC=runtime error: invalid memory address or nil pointer dereference"},
+	}
+	for _, tt := range tests {
+		f := Field{Key: "k", Interface: panicking{}, Type: tt.t}
+		enc := NewMapObjectEncoder()
+		assert.NotPanics(t, func() { f.AddTo(enc) }, "Unexpected panic when encoding a field panics.")
+		assert.Equal(t, map[string]interface{}{"kError": tt.want}, enc.Fields, "Expected the failing field to be replaced.")
+	}
+}
+
 func TestFields(t *testing.T) {
 	tests := []struct {
 		t     FieldType
@@ -0,0 +1,254 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.uber.org/multierr"
+)
+
+type flightRecord struct {
+	core   Core
+	ent    Entry
+	fields []Field
+}
+
+type flightRecorder struct {
+	trigger Level
+
+	mu sync.Mutex
+	// ring holds count records starting at head.
+	ring  []flightRecord
+	head  int
+	count int
+}
+
+type flightRecorderCore struct {
+	Core
+	r *flightRecorder
+}
+
+// NewFlightRecorderCore wraps a Core and keeps the last size entries which
+// the wrapped Core doesn't log in memory, at every level. When an entry at
+// or above the trigger level is written, the kept entries are written to the
+// wrapped Core first, oldest first, bypassing its level.
+//
+// The returned Core reports the wrapped Core's level from Enabled, but its
+// Check accepts entries at every level, so it records entries only when
+// checked directly, as a Logger does, rather than by wrappers which consult
+// Enabled first, such as NewSampler. Entries which the wrapped Core's Check
+// lets through are written to the Cores it chose.
+//
+// Kept entries aren't encoded, since encoding is up to the wrapped Core.
+// Instead, fields which could change after the entry is kept are evaluated
+// when it's kept: Stringers and errors are kept as their messages,
+// reflected values as their JSON, and marshalers as the values they add.
+// This costs every recorded entry that work, whether or not it's ever
+// flushed, and times and durations added by marshalers are later encoded
+// like reflected values rather than by the Encoder's TimeEncoder and
+// DurationEncoder.
+func NewFlightRecorderCore(core Core, size int, trigger Level) Core {
+	if size < 1 {
+		size = 1
+	}
+	return &flightRecorderCore{
+		Core: core,
+		r: &flightRecorder{
+			trigger: trigger,
+			ring:    make([]flightRecord, size),
+		},
+	}
+}
+
+func (c *flightRecorderCore) With(fields []Field) Core {
+	return &flightRecorderCore{
+		Core: c.Core.With(fields),
+		r:    c.r,
+	}
+}
+
+func (c *flightRecorderCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	return checkIntercepted(c, c.Core, ent, ce, true)
+}
+
+func (c *flightRecorderCore) Write(ent Entry, fields []Field) error {
+	var out multiCore
+	if c.Core.Enabled(ent.Level) {
+		out = multiCore{c.Core}
+	}
+	return c.writeTo(out, ent, fields)
+}
+
+// writeTo writes the entry to out, the Cores which the wrapped Core's Check
+// chose for it. If there are none, the entry is kept instead.
+func (c *flightRecorderCore) writeTo(out multiCore, ent Entry, fields []Field) error {
+	logged := len(out) > 0
+	if ent.Level >= c.r.trigger {
+		err := c.r.flush()
+		if logged {
+			err = multierr.Append(err, out.Write(ent, fields))
+		}
+		return err
+	}
+	if logged {
+		return out.Write(ent, fields)
+	}
+	c.r.record(flightRecord{core: c.Core, ent: ent, fields: freezeFields(fields)})
+	return nil
+}
+
+func (r *flightRecorder) record(rec flightRecord) {
+	r.mu.Lock()
+	if r.count == len(r.ring) {
+		// Overwrite the oldest record.
+		r.ring[r.head] = rec
+		r.head = (r.head + 1) % len(r.ring)
+	} else {
+		r.ring[(r.head+r.count)%len(r.ring)] = rec
+		r.count++
+	}
+	r.mu.Unlock()
+}
+
+func (r *flightRecorder) flush() error {
+	r.mu.Lock()
+	recs := make([]flightRecord, 0, r.count)
+	for i := 0; i < r.count; i++ {
+		j := (r.head + i) % len(r.ring)
+		recs = append(recs, r.ring[j])
+		r.ring[j] = flightRecord{}
+	}
+	r.head, r.count = 0, 0
+	r.mu.Unlock()
+
+	var err error
+	for _, rec := range recs {
+		err = multierr.Append(err, rec.core.Write(rec.ent, rec.fields))
+	}
+	return err
+}
+
+// freezeFields copies fields, evaluating the lazy ones so that later changes
+// to the logged values don't affect the kept entry.
+func freezeFields(fields []Field) []Field {
+	frozen := make([]Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case StringerType:
+			if s, ok := stringOf(f); ok {
+				f = Field{Key: f.Key, Type: StringType, String: s}
+			}
+		case ErrorType:
+			if err, ok := freezeError(f.Interface.(error)); ok {
+				f.Interface = err
+			}
+		case ReflectType:
+			// Values which fail to marshal are kept as they are, like below.
+			if b, ok := marshalJSON(f.Interface); ok {
+				f.Interface = json.RawMessage(b)
+			}
+		case ObjectMarshalerType, ArrayMarshalerType:
+			// Fields which fail to marshal are kept as they are, so that
+			// the failure is reported when they're written.
+			enc := NewMapObjectEncoder()
+			f.AddTo(enc)
+			if v, ok := enc.Fields[f.Key]; ok && len(enc.Fields) == 1 {
+				f = Field{Key: f.Key, Type: ReflectType, Interface: v}
+			}
+		}
+		frozen[i] = f
+	}
+	return frozen
+}
+
+func marshalJSON(v interface{}) (b []byte, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	b, err := json.Marshal(v)
+	return b, err == nil
+}
+
+// A frozenError keeps the messages of an error, and encodes like it.
+type frozenError struct {
+	msg, verbose string
+}
+
+func (e frozenError) Error() string {
+	return e.msg
+}
+
+func (e frozenError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		io.WriteString(s, e.verbose)
+		return
+	}
+	io.WriteString(s, e.msg)
+}
+
+// A frozenErrorGroup keeps the message of an errorGroup, and its frozen
+// errors.
+type frozenErrorGroup struct {
+	msg  string
+	errs []error
+}
+
+func (e frozenErrorGroup) Error() string {
+	return e.msg
+}
+
+func (e frozenErrorGroup) Errors() []error {
+	return e.errs
+}
+
+// freezeError evaluates err's messages, reporting false if that panics.
+func freezeError(err error) (frozen error, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	if group, isGroup := err.(errorGroup); isGroup {
+		errs := group.Errors()
+		frozenErrs := make([]error, len(errs))
+		for i, e := range errs {
+			if e == nil {
+				continue
+			}
+			if frozenErrs[i], ok = freezeError(e); !ok {
+				return nil, false
+			}
+		}
+		return frozenErrorGroup{err.Error(), frozenErrs}, true
+	}
+	if _, isFormatter := err.(fmt.Formatter); isFormatter {
+		return frozenError{err.Error(), fmt.Sprintf("%+v", err)}, true
+	}
+	// Without a Formatter, the verbose message isn't encoded.
+	msg := err.Error()
+	return frozenError{msg, msg}, true
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mutableStringer struct{ s string }
+
+type mutableError struct{ msg string }
+
+func (e *mutableError) Error() string { return e.msg }
+
+func (m *mutableStringer) String() string { return m.s }
+
+func writeRecorded(core Core, lvl Level, msg string, fields ...Field) {
+	checkAndWrite(core, Entry{Level: lvl, Message: msg}, fields...)
+}
+
+func loggedMessages(logs *observer.ObservedLogs) []string {
+	var msgs []string
+	for _, l := range logs.TakeAll() {
+		msgs = append(msgs, l.Message)
+	}
+	return msgs
+}
+
+func TestFlightRecorderCore(t *testing.T) {
+	obs, logs := observer.New(InfoLevel)
+	core := NewFlightRecorderCore(obs, 3, ErrorLevel)
+
+	assert.Equal(t, InfoLevel, LevelOf(core), "Expected the wrapped Core's level.")
+
+	for _, msg := range []string{"d0", "d1", "d2", "d3"} {
+		writeRecorded(core, DebugLevel, msg)
+	}
+	writeRecorded(core, InfoLevel, "i0")
+	assert.Equal(t, []string{"i0"}, loggedMessages(logs), "Expected only enabled entries to be written.")
+
+	writeRecorded(core, ErrorLevel, "e0")
+	assert.Equal(t, []string{"d1", "d2", "d3", "e0"}, loggedMessages(logs), "Expected the last entries before the trigger.")
+
+	writeRecorded(core, WarnLevel, "w0")
+	writeRecorded(core, ErrorLevel, "e1")
+	assert.Equal(t, []string{"w0", "e1"}, loggedMessages(logs), "Expected the ring to be emptied by a flush.")
+}
+
+func TestFlightRecorderCoreContext(t *testing.T) {
+	obs, logs := observer.New(InfoLevel)
+	core := NewFlightRecorderCore(obs, 10, ErrorLevel)
+
+	s := &mutableStringer{"before"}
+	withCore := core.With([]Field{makeInt64Field("ctx", 1)})
+	writeRecorded(withCore, DebugLevel, "d0", Field{Key: "s", Type: StringerType, Interface: s})
+	s.s = "after"
+	writeRecorded(core, ErrorLevel, "e0")
+
+	assert.Equal(t, []observer.LoggedEntry{
+		{
+			Entry: Entry{Level: DebugLevel, Message: "d0"},
+			Context: []Field{
+				makeInt64Field("ctx", 1),
+				{Key: "s", Type: StringType, String: "before"},
+			},
+		},
+		{Entry: Entry{Level: ErrorLevel, Message: "e0"}, Context: []Field{}},
+	}, logs.AllUntimed(), "Expected kept entries to keep their context and field values.")
+}
+
+func TestFlightRecorderCoreRespectsWrappedCheck(t *testing.T) {
+	info, infoLogs := observer.New(InfoLevel)
+	errs, errLogs := observer.New(ErrorLevel)
+	core := NewFlightRecorderCore(NewTee(info, errs), 10, DPanicLevel)
+
+	writeRecorded(core, InfoLevel, "i0")
+	writeRecorded(core, ErrorLevel, "e0")
+	assert.Equal(t, []string{"i0", "e0"}, loggedMessages(infoLogs), "Unexpected entries on the InfoLevel Core.")
+	assert.Equal(t, []string{"e0"}, loggedMessages(errLogs), "Expected the ErrorLevel Core to skip InfoLevel entries.")
+}
+
+func TestFlightRecorderCoreFreezesValues(t *testing.T) {
+	obs, logs := observer.New(InfoLevel)
+	core := NewFlightRecorderCore(obs, 10, ErrorLevel)
+
+	m := map[string]int{"n": 1}
+	err := &mutableError{"before"}
+	writeRecorded(core, DebugLevel, "d0",
+		Field{Key: "m", Type: ReflectType, Interface: m},
+		Field{Key: "error", Type: ErrorType, Interface: err},
+	)
+	m["n"] = 2
+	err.msg = "after"
+	writeRecorded(core, ErrorLevel, "e0")
+
+	enc := NewMapObjectEncoder()
+	for _, f := range logs.All()[0].Context {
+		f.AddTo(enc)
+	}
+	assert.Equal(t, "before", enc.Fields["error"], "Expected the error message to be frozen.")
+	b, jerr := json.Marshal(enc.Fields["m"])
+	require.NoError(t, jerr, "Failed to marshal reflected field.")
+	assert.Equal(t, `{"n":1}`, string(b), "Expected the reflected value to be frozen.")
+}
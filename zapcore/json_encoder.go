@@ -379,6 +379,15 @@ func (enc *jsonEncoder) truncate() {
 	enc.buf.Reset()
 }
 
+func (enc *jsonEncoder) mark() encoderMark {
+	return encoderMark{len: enc.buf.Len(), openNamespaces: enc.openNamespaces}
+}
+
+func (enc *jsonEncoder) restore(m encoderMark, _ string) {
+	enc.buf.Truncate(m.len)
+	enc.openNamespaces = m.openNamespaces
+}
+
 func (enc *jsonEncoder) closeOpenNamespaces() {
 	for i := 0; i < enc.openNamespaces; i++ {
 		enc.buf.AppendByte('}')
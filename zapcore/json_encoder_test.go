@@ -120,3 +120,39 @@ func TestJSONEncodeEntry(t *testing.T) {
 		})
 	}
 }
+
+type panicObject struct{}
+
+func (panicObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("partial", "value")
+	enc.OpenNamespace("ns")
+	enc.AddArray("arr", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		enc.AppendInt(1)
+		panic("boom")
+	}))
+	return nil
+}
+
+type panicJSON struct{}
+
+func (panicJSON) MarshalJSON() ([]byte, error) { panic("json") }
+
+func TestJSONEncodeEntryPanics(t *testing.T) {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{MessageKey: "M"})
+	enc.AddString("ctx", "value")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Message: "m"}, []zapcore.Field{
+		zap.String("before", "ok"),
+		zap.Object("obj", panicObject{}),
+		zap.Reflect("refl", panicJSON{}),
+		zap.String("after", "ok"),
+	})
+	if assert.NoError(t, err, "Unexpected JSON encoding error.") {
+		assert.Equal(
+			t,
+			`{"M":"m","ctx":"value","before":"ok","objError":"PANIC=boom","reflError":"PANIC=json","after":"ok"}`+"\n",
+			buf.String(),
+			"Expected partial output of the failing field to be discarded.",
+		)
+	}
+	buf.Free()
+}
@@ -43,6 +43,9 @@ const (
 	// ErrorLevel logs are high-priority. If an application is running smoothly,
 	// it shouldn't generate any error-level logs.
 	ErrorLevel
+	// DPanicLevel logs are particularly important errors. In development the
+	// logger panics after writing the message.
+	DPanicLevel
 	// PanicLevel logs a message, then panics.
 	PanicLevel
 	// FatalLevel logs a message, then calls os.Exit(1).
@@ -50,8 +53,25 @@ const (
 
 	_minLevel = DebugLevel
 	_maxLevel = FatalLevel
+
+	// InvalidLevel is an invalid value for Level.
+	//
+	// Core implementations may panic if they see messages of this level.
+	InvalidLevel = _maxLevel + 1
 )
 
+// LevelOf reports the minimum enabled log level for the given LevelEnabler
+// from zap's supported log levels, or InvalidLevel if none of them are
+// enabled.
+func LevelOf(enab LevelEnabler) Level {
+	for lvl := _minLevel; lvl <= _maxLevel; lvl++ {
+		if enab.Enabled(lvl) {
+			return lvl
+		}
+	}
+	return InvalidLevel
+}
+
 // String returns a lower-case ASCII representation of the log level.
 func (l Level) String() string {
 	switch l {
@@ -63,6 +83,8 @@ func (l Level) String() string {
 		return "warn"
 	case ErrorLevel:
 		return "error"
+	case DPanicLevel:
+		return "dpanic"
 	case PanicLevel:
 		return "panic"
 	case FatalLevel:
@@ -85,6 +107,8 @@ func (l Level) CapitalString() string {
 		return "WARN"
 	case ErrorLevel:
 		return "ERROR"
+	case DPanicLevel:
+		return "DPANIC"
 	case PanicLevel:
 		return "PANIC"
 	case FatalLevel:
@@ -126,6 +150,8 @@ func (l *Level) unmarshalText(text []byte) bool {
 		*l = WarnLevel
 	case "error", "ERROR":
 		*l = ErrorLevel
+	case "dpanic", "DPANIC":
+		*l = DPanicLevel
 	case "panic", "PANIC":
 		*l = PanicLevel
 	case "fatal", "FATAL":
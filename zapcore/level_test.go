@@ -31,13 +31,14 @@ import (
 
 func TestLevelString(t *testing.T) {
 	tests := map[Level]string{
-		DebugLevel: "debug",
-		InfoLevel:  "info",
-		WarnLevel:  "warn",
-		ErrorLevel: "error",
-		PanicLevel: "panic",
-		FatalLevel: "fatal",
-		Level(-42): "Level(-42)",
+		DebugLevel:  "debug",
+		InfoLevel:   "info",
+		WarnLevel:   "warn",
+		ErrorLevel:  "error",
+		DPanicLevel: "dpanic",
+		PanicLevel:  "panic",
+		FatalLevel:  "fatal",
+		Level(-42):  "Level(-42)",
 	}
 
 	for lvl, stringLevel := range tests {
@@ -56,6 +57,7 @@ func TestLevelText(t *testing.T) {
 		{"", InfoLevel}, // make the zero value useful
 		{"warn", WarnLevel},
 		{"error", ErrorLevel},
+		{"dpanic", DPanicLevel},
 		{"panic", PanicLevel},
 		{"fatal", FatalLevel},
 	}
@@ -83,6 +85,7 @@ func TestCapitalLevelsParse(t *testing.T) {
 		{"INFO", InfoLevel},
 		{"WARN", WarnLevel},
 		{"ERROR", ErrorLevel},
+		{"DPANIC", DPanicLevel},
 		{"PANIC", PanicLevel},
 		{"FATAL", FatalLevel},
 	}
@@ -104,6 +107,7 @@ func TestWeirdLevelsParse(t *testing.T) {
 		{"Info", InfoLevel},
 		{"Warn", WarnLevel},
 		{"Error", ErrorLevel},
+		{"DPanic", DPanicLevel},
 		{"Panic", PanicLevel},
 		{"Fatal", FatalLevel},
 
@@ -112,6 +116,7 @@ func TestWeirdLevelsParse(t *testing.T) {
 		{"InFo", InfoLevel},
 		{"WaRn", WarnLevel},
 		{"ErRor", ErrorLevel},
+		{"DpAnIc", DPanicLevel},
 		{"PaNiC", PanicLevel},
 		{"FaTaL", FatalLevel},
 	}
@@ -154,7 +159,7 @@ func TestLevelAsFlagValue(t *testing.T) {
 	fs.SetOutput(&buf)
 	fs.Var(&lvl, "level", "log level")
 
-	for _, expected := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel} {
+	for _, expected := range []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel, PanicLevel, FatalLevel} {
 		assert.NoError(t, fs.Parse([]string{"-level", expected.String()}))
 		assert.Equal(t, expected, lvl, "Unexpected level after parsing flag.")
 		assert.Equal(t, expected, lvl.Get(), "Unexpected output using flag.Getter API.")
@@ -170,3 +175,26 @@ func TestLevelAsFlagValue(t *testing.T) {
 		"Unexpected error output from invalid flag input.",
 	)
 }
+
+func TestLevelOf(t *testing.T) {
+	tests := []struct {
+		desc string
+		give LevelEnabler
+		want Level
+	}{
+		{desc: "debug", give: DebugLevel, want: DebugLevel},
+		{desc: "info", give: InfoLevel, want: InfoLevel},
+		{desc: "warn", give: WarnLevel, want: WarnLevel},
+		{desc: "error", give: ErrorLevel, want: ErrorLevel},
+		{desc: "dpanic", give: DPanicLevel, want: DPanicLevel},
+		{desc: "panic", give: PanicLevel, want: PanicLevel},
+		{desc: "fatal", give: FatalLevel, want: FatalLevel},
+		{desc: "nothing enabled", give: InvalidLevel, want: InvalidLevel},
+		{desc: "below minimum", give: Level(-42), want: DebugLevel},
+		{desc: "nop core", give: NewNopCore(), want: InvalidLevel},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, LevelOf(tt.give), "Unexpected level for %s.", tt.desc)
+	}
+}
@@ -41,6 +41,14 @@ func NewMapObjectEncoder() *MapObjectEncoder {
 	}
 }
 
+func (m *MapObjectEncoder) mark() encoderMark {
+	return encoderMark{}
+}
+
+func (m *MapObjectEncoder) restore(_ encoderMark, key string) {
+	delete(m.cur, key)
+}
+
 // AddArray implements ObjectEncoder.
 func (m *MapObjectEncoder) AddArray(key string, v ArrayMarshaler) error {
 	arr := &sliceArrayEncoder{elems: make([]interface{}, 0)}
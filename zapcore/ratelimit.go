@@ -0,0 +1,341 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	_defaultRateLimitMaxKeys  = 10000
+	_defaultRateLimitInterval = time.Minute
+
+	// RateLimitMessage is the message of the summary entries written by a
+	// rate-limiting Core.
+	RateLimitMessage = "rate limit dropped entries"
+	// RateLimitDroppedKey is the key of the field holding the number of
+	// dropped entries in a summary.
+	RateLimitDroppedKey = "dropped"
+)
+
+// RateLimitOption configures a rate-limiting Core.
+type RateLimitOption interface {
+	apply(*rateLimiter)
+}
+
+type rateLimitOptionFunc func(*rateLimiter)
+
+func (f rateLimitOptionFunc) apply(r *rateLimiter) {
+	f(r)
+}
+
+// RateLimitMaxKeys bounds the number of keys whose state is kept. When the
+// bound is reached, the least recently used key is forgotten. The default is
+// 10000.
+func RateLimitMaxKeys(n int) RateLimitOption {
+	return rateLimitOptionFunc(func(r *rateLimiter) {
+		if n > 0 {
+			r.maxKeys = n
+		}
+	})
+}
+
+// RateLimitSummaryInterval sets how often summaries of dropped entries are
+// written. The default is one minute.
+func RateLimitSummaryInterval(d time.Duration) RateLimitOption {
+	return rateLimitOptionFunc(func(r *rateLimiter) {
+		r.interval = d
+	})
+}
+
+// RateLimitClock sets the clock which decides when summaries are due and
+// stamps them. It defaults to DefaultClock.
+func RateLimitClock(clock Clock) RateLimitOption {
+	return rateLimitOptionFunc(func(r *rateLimiter) {
+		if clock != nil {
+			r.clock = clock
+		}
+	})
+}
+
+type rateLimiter struct {
+	// root is the wrapped Core, without any context added with With, which
+	// summaries are written to.
+	root     Core
+	key      string
+	burst    float64
+	rate     float64
+	maxKeys  int
+	interval time.Duration
+	clock    Clock
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// lru holds *tokenBucket, most recently used first.
+	lru *list.List
+	// evicted counts drops of forgotten keys since the last summary.
+	evicted     uint64
+	nextSummary time.Time
+
+	// stop ends the goroutine writing periodic summaries, which then closes
+	// stopped.
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+type tokenBucket struct {
+	value   string
+	tokens  float64
+	last    time.Time
+	dropped uint64
+}
+
+type rateLimitCore struct {
+	Core
+	r *rateLimiter
+	// value is the key field's value if it was added with With.
+	value    string
+	hasValue bool
+}
+
+// NewRateLimitCore wraps a Core and rate-limits entries per value of the
+// field named key, found either at the log site or in fields added with
+// With. Each value gets a token bucket holding up to burst entries, refilled
+// at rate entries per second of entry time. Entries without the field, and
+// entries above ErrorLevel, are never limited.
+//
+// Entries are passed on only to the Cores chosen by the wrapped Core's Check,
+// and entries which no Core would log aren't counted.
+//
+// Dropped entries are counted, and the counts are written to core as
+// WarnLevel summary entries every summary interval of the clock, by a
+// background goroutine or by the first Write after the interval if that comes
+// first, and by Sync and Close. There is one summary per key value, plus one
+// without the key field for values forgotten in the meantime. Like other
+// entries, summaries go through core's Check. Close stops the goroutine
+// writing the periodic summaries.
+func NewRateLimitCore(core Core, key string, burst int, rate float64, opts ...RateLimitOption) Core {
+	if burst < 1 {
+		burst = 1
+	}
+	r := &rateLimiter{
+		root:     core,
+		key:      key,
+		burst:    float64(burst),
+		rate:     rate,
+		maxKeys:  _defaultRateLimitMaxKeys,
+		interval: _defaultRateLimitInterval,
+		clock:    DefaultClock,
+		buckets:  make(map[string]*list.Element),
+		lru:      list.New(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(r)
+	}
+	if r.interval > 0 {
+		go r.summarizeEvery(r.interval)
+	} else {
+		close(r.stopped)
+	}
+	return &rateLimitCore{Core: core, r: r}
+}
+
+func (c *rateLimitCore) With(fields []Field) Core {
+	clone := &rateLimitCore{
+		Core:     c.Core.With(fields),
+		r:        c.r,
+		value:    c.value,
+		hasValue: c.hasValue,
+	}
+	if v, ok := fieldValue(fields, c.r.key); ok {
+		clone.value, clone.hasValue = v, true
+	}
+	return clone
+}
+
+func (c *rateLimitCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return checkIntercepted(c, c.Core, ent, ce, false)
+}
+
+func (c *rateLimitCore) Write(ent Entry, fields []Field) error {
+	return c.writeTo(multiCore{c.Core}, ent, fields)
+}
+
+func (c *rateLimitCore) writeTo(out multiCore, ent Entry, fields []Field) error {
+	t := ent.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	value, ok := c.value, c.hasValue
+	if v, found := fieldValue(fields, c.r.key); found {
+		value, ok = v, true
+	}
+
+	allowed := true
+	c.r.mu.Lock()
+	if ok && ent.Level <= ErrorLevel {
+		allowed = c.r.allow(value, t)
+	}
+	now := c.r.clock.Now()
+	var summaries [][]Field
+	if !now.Before(c.r.nextSummary) {
+		summaries = c.r.summaries(now)
+	}
+	c.r.mu.Unlock()
+
+	c.r.writeSummaries(now, summaries)
+	if !allowed {
+		return nil
+	}
+	return out.Write(ent, fields)
+}
+
+func (c *rateLimitCore) Sync() error {
+	c.r.flushSummaries()
+	return c.Core.Sync()
+}
+
+func (c *rateLimitCore) Close() error {
+	c.r.stopOnce.Do(func() {
+		close(c.r.stop)
+		<-c.r.stopped
+	})
+	c.r.flushSummaries()
+	return c.Core.Close()
+}
+
+func (r *rateLimiter) summarizeEvery(d time.Duration) {
+	defer close(r.stopped)
+	ticker := r.clock.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.flushSummaries()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *rateLimiter) flushSummaries() {
+	t := r.clock.Now()
+	r.mu.Lock()
+	summaries := r.summaries(t)
+	r.mu.Unlock()
+
+	r.writeSummaries(t, summaries)
+}
+
+func (r *rateLimiter) writeSummaries(t time.Time, summaries [][]Field) {
+	ent := Entry{Level: WarnLevel, Time: t, Message: RateLimitMessage}
+	for _, fields := range summaries {
+		// Like the sampler, there's nowhere to report errors from here.
+		if ce := r.root.Check(ent, nil); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+}
+
+// allow takes a token from the bucket of value, reporting whether there was
+// one. It must be called with mu held.
+func (r *rateLimiter) allow(value string, t time.Time) bool {
+	var b *tokenBucket
+	if elem, ok := r.buckets[value]; ok {
+		r.lru.MoveToFront(elem)
+		b = elem.Value.(*tokenBucket)
+		if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+			b.tokens += elapsed * r.rate
+			if b.tokens > r.burst {
+				b.tokens = r.burst
+			}
+			b.last = t
+		}
+	} else {
+		b = &tokenBucket{value: value, tokens: r.burst, last: t}
+		r.buckets[value] = r.lru.PushFront(b)
+		if r.lru.Len() > r.maxKeys {
+			oldest := r.lru.Remove(r.lru.Back()).(*tokenBucket)
+			delete(r.buckets, oldest.value)
+			r.evicted += oldest.dropped
+		}
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	b.dropped++
+	return false
+}
+
+// summaries resets the drop counts, returning the fields of the summary
+// entries to write. It must be called with mu held.
+func (r *rateLimiter) summaries(t time.Time) [][]Field {
+	r.nextSummary = t.Add(r.interval)
+
+	var fields [][]Field
+	for elem := r.lru.Front(); elem != nil; elem = elem.Next() {
+		b := elem.Value.(*tokenBucket)
+		if b.dropped == 0 {
+			continue
+		}
+		fields = append(fields, []Field{
+			{Key: r.key, Type: StringType, String: b.value},
+			{Key: RateLimitDroppedKey, Type: Uint64Type, Integer: int64(b.dropped)},
+		})
+		b.dropped = 0
+	}
+	if r.evicted > 0 {
+		fields = append(fields, []Field{
+			{Key: RateLimitDroppedKey, Type: Uint64Type, Integer: int64(r.evicted)},
+		})
+		r.evicted = 0
+	}
+	return fields
+}
+
+// fieldValue returns the string form of the value of the last field named
+// key.
+func fieldValue(fields []Field, key string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		f := fields[i]
+		if f.Key != key || f.Type == NamespaceType || f.Type == SkipType {
+			continue
+		}
+		if f.Type == StringType {
+			return f.String, true
+		}
+		enc := NewMapObjectEncoder()
+		f.AddTo(enc)
+		return fmt.Sprint(enc.Fields[f.Key]), true
+	}
+	return "", false
+}
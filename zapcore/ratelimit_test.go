@@ -0,0 +1,197 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tenantField(v string) Field {
+	return Field{Key: "tenant", Type: StringType, String: v}
+}
+
+func writeLimited(core Core, t time.Time, fields ...Field) {
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m", Time: t}, fields...)
+}
+
+func rateLimitSummaries(logs *observer.ObservedLogs) map[string]interface{} {
+	summaries := make(map[string]interface{})
+	for _, l := range logs.FilterMessage(RateLimitMessage).AllUntimed() {
+		m := l.ContextMap()
+		tenant, _ := m["tenant"].(string)
+		summaries[tenant] = m[RateLimitDroppedKey]
+	}
+	return summaries
+}
+
+func TestRateLimitCoreBurstAndRefill(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(obs, "tenant", 2, 1, RateLimitSummaryInterval(time.Hour))
+
+	t0 := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		writeLimited(core, t0, tenantField("noisy"))
+	}
+	writeLimited(core, t0, tenantField("quiet"))
+	writeLimited(core, t0)
+	writeLimited(core, t0)
+	assert.Equal(t, 5, logs.FilterMessage("m").Len(), "Expected burst per key and no limit without the key.")
+
+	// Half a second refills half a token, which isn't enough.
+	writeLimited(core, t0.Add(500*time.Millisecond), tenantField("noisy"))
+	writeLimited(core, t0.Add(time.Second), tenantField("noisy"))
+	assert.Equal(t, 6, logs.FilterMessage("m").Len(), "Expected tokens to refill over entry time.")
+
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, map[string]interface{}{"noisy": uint64(4)}, rateLimitSummaries(logs), "Unexpected summaries.")
+	assert.Equal(t, WarnLevel, logs.FilterMessage(RateLimitMessage).All()[0].Level, "Unexpected summary level.")
+}
+
+func TestRateLimitCoreWith(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(obs, "tenant", 1, 0)
+	tenantCore := core.With([]Field{tenantField("a")})
+
+	t0 := time.Unix(1000, 0)
+	writeLimited(tenantCore, t0)
+	writeLimited(tenantCore, t0)
+	writeLimited(core, t0, tenantField("a"))
+	// Log-site fields take precedence over context.
+	writeLimited(tenantCore, t0, tenantField("b"))
+	assert.Equal(t, 2, logs.FilterMessage("m").Len(), "Expected context fields to select the bucket.")
+}
+
+func TestRateLimitCoreSummaryInterval(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	t0 := time.Unix(1000, 0)
+	clock := &manualClock{now: t0}
+	core := NewRateLimitCore(obs, "tenant", 1, 0, RateLimitSummaryInterval(time.Minute), RateLimitClock(clock))
+	defer core.Close()
+
+	for i := 0; i < 3; i++ {
+		writeLimited(core, t0, tenantField("a"))
+	}
+	// Entry time doesn't decide when summaries are due.
+	writeLimited(core, t0.Add(time.Hour), tenantField("b"))
+	assert.Empty(t, rateLimitSummaries(logs), "Expected no summary before the interval ends.")
+
+	clock.Add(time.Minute)
+	writeLimited(core, t0, tenantField("c"))
+	assert.Equal(t, map[string]interface{}{"a": uint64(2)}, rateLimitSummaries(logs), "Expected a summary after the interval.")
+	assert.Equal(t, RateLimitMessage, logs.All()[2].Message, "Expected the summary before the triggering entry.")
+	assert.Equal(t, t0.Add(time.Minute), logs.All()[2].Time, "Expected the summary to be stamped by the clock.")
+}
+
+func TestRateLimitCoreMaxKeys(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(obs, "tenant", 1, 0, RateLimitMaxKeys(2), RateLimitSummaryInterval(time.Hour))
+
+	t0 := time.Unix(1000, 0)
+	writeLimited(core, t0, tenantField("a"))
+	writeLimited(core, t0, tenantField("a"))
+	writeLimited(core, t0, tenantField("b"))
+	writeLimited(core, t0, tenantField("c")) // forgets a
+	writeLimited(core, t0, tenantField("a"))
+	assert.Equal(t, 4, logs.FilterMessage("m").Len(), "Expected forgotten keys to start with a full bucket.")
+
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, map[string]interface{}{"": uint64(1)}, rateLimitSummaries(logs), "Expected drops of forgotten keys to be reported.")
+}
+
+func TestRateLimitCoreSkipsSevereEntries(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(obs, "tenant", 1, 0)
+
+	for i := 0; i < 3; i++ {
+		core.Write(Entry{Level: DPanicLevel, Message: "m"}, []Field{tenantField("a")})
+	}
+	assert.Equal(t, 3, logs.FilterMessage("m").Len(), "Expected entries above ErrorLevel to pass through.")
+}
+
+func TestRateLimitCoreSummariesSkipContext(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	t0 := time.Unix(1000, 0)
+	clock := &manualClock{now: t0}
+	core := NewRateLimitCore(obs, "tenant", 1, 0, RateLimitSummaryInterval(time.Hour), RateLimitClock(clock))
+	defer core.Close()
+
+	writeLimited(core, t0, tenantField("b"))
+	writeLimited(core, t0, tenantField("b"))
+	clock.Add(time.Hour)
+	writeLimited(core.With([]Field{tenantField("a")}), t0)
+
+	summaries := logs.FilterMessage(RateLimitMessage).AllUntimed()
+	require.Equal(t, 1, len(summaries), "Expected a summary after the interval.")
+	assert.Equal(t, []Field{
+		tenantField("b"),
+		{Key: RateLimitDroppedKey, Type: Uint64Type, Integer: 1},
+	}, summaries[0].Context, "Expected summaries to be written without the writer's context.")
+}
+
+func TestRateLimitCorePeriodicSummaries(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(obs, "tenant", 1, 0, RateLimitSummaryInterval(10*time.Millisecond))
+
+	t0 := time.Now()
+	writeLimited(core, t0, tenantField("a"))
+	writeLimited(core, t0, tenantField("a"))
+	for i := 0; i < 100 && logs.FilterMessage(RateLimitMessage).Len() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, map[string]interface{}{"a": uint64(1)}, rateLimitSummaries(logs), "Expected a summary without further writes.")
+
+	require.NoError(t, core.Close(), "Unexpected error closing.")
+	assert.NoError(t, core.Close(), "Expected closing again to be a no-op.")
+}
+
+func TestRateLimitCoreRespectsWrappedCheck(t *testing.T) {
+	info, infoLogs := observer.New(InfoLevel)
+	errs, errLogs := observer.New(ErrorLevel)
+	core := NewRateLimitCore(NewTee(info, errs), "tenant", 1, 0, RateLimitSummaryInterval(time.Hour))
+	defer core.Close()
+
+	t0 := time.Unix(1000, 0)
+	writeLimited(core, t0, tenantField("a"))
+	writeLimited(core, t0, tenantField("a"))
+	require.NoError(t, core.Sync(), "Unexpected error syncing.")
+	assert.Equal(t, 1, infoLogs.FilterMessage("m").Len(), "Expected the entry on the enabled Core.")
+	assert.Equal(t, map[string]interface{}{"a": uint64(1)}, rateLimitSummaries(infoLogs), "Expected a summary on the enabled Core.")
+	assert.Equal(t, 0, errLogs.Len(), "Expected neither entries nor WarnLevel summaries on the Core which logs ErrorLevel.")
+}
+
+func TestRateLimitCoreRespectsWrappedSampler(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRateLimitCore(NewSampler(obs, time.Minute, 2, 0), "tenant", 10, 0, RateLimitSummaryInterval(time.Hour))
+	defer core.Close()
+
+	t0 := time.Unix(1000, 0)
+	for i := 0; i < 5; i++ {
+		writeLimited(core, t0, tenantField("a"))
+	}
+	assert.Equal(t, 2, logs.FilterMessage("m").Len(), "Expected the wrapped sampler to drop entries.")
+}
@@ -0,0 +1,549 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"time"
+)
+
+// RedactedValue replaces the value of fields masked by a RedactRule.
+const RedactedValue = "[REDACTED]"
+
+var errEmptyRedactRule = errors.New("redact rule must set Key, KeyGlob or Value")
+
+// A RedactAction decides what happens to a value matched by a RedactRule.
+type RedactAction uint8
+
+const (
+	// RedactMask replaces the value with RedactedValue.
+	RedactMask RedactAction = iota
+	// RedactHash replaces the value with a truncated SHA-256 digest, so equal
+	// values can still be correlated without being readable.
+	RedactHash
+	// RedactDrop removes the field (or array element) entirely.
+	RedactDrop
+)
+
+// A RedactRule describes values which must never reach the Encoder verbatim.
+//
+// A rule matches a field whose key equals Key, or whose key matches KeyGlob
+// (using path.Match syntax). When a key matches, the whole value is redacted,
+// whatever its type. Value matches string values instead; only the matching
+// parts of the string are masked or hashed, while RedactDrop drops the whole
+// field.
+//
+// At least one of Key, KeyGlob and Value must be set.
+type RedactRule struct {
+	Key     string
+	KeyGlob string
+	Value   *regexp.Regexp
+	Action  RedactAction
+}
+
+type redactor struct {
+	rules []RedactRule
+}
+
+// NewRedactCore wraps a Core and redacts both context fields added by With
+// and fields passed at the log site, before they reach the wrapped Core.
+// Rules also apply to everything produced by ObjectMarshalers,
+// ArrayMarshalers and reflected values (keys of reflected values are their
+// JSON names).
+//
+// Rules are applied in order and the first matching key rule wins.
+//
+// Entries are passed on only to the Cores chosen by the wrapped Core's
+// Check, so wrapped Tees and samplers filter entries as usual.
+func NewRedactCore(core Core, rules ...RedactRule) (Core, error) {
+	for _, rule := range rules {
+		if rule.Key == "" && rule.KeyGlob == "" && rule.Value == nil {
+			return nil, errEmptyRedactRule
+		}
+		if rule.KeyGlob != "" {
+			if _, err := path.Match(rule.KeyGlob, ""); err != nil {
+				return nil, fmt.Errorf("invalid redact key glob %q: %v", rule.KeyGlob, err)
+			}
+		}
+	}
+	r := &redactor{rules: append([]RedactRule(nil), rules...)}
+	return &redactCore{Core: core, r: r}, nil
+}
+
+type redactCore struct {
+	Core
+	r *redactor
+}
+
+func (c *redactCore) With(fields []Field) Core {
+	return &redactCore{
+		Core: c.Core.With(c.r.redactFields(fields)),
+		r:    c.r,
+	}
+}
+
+func (c *redactCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	return checkIntercepted(c, c.Core, ent, ce, false)
+}
+
+func (c *redactCore) Write(ent Entry, fields []Field) error {
+	return c.writeTo(multiCore{c.Core}, ent, fields)
+}
+
+func (c *redactCore) writeTo(out multiCore, ent Entry, fields []Field) error {
+	return out.Write(ent, c.r.redactFields(fields))
+}
+
+// matchKey returns the action of the first key rule matching key.
+func (r *redactor) matchKey(key string) (RedactAction, bool) {
+	for _, rule := range r.rules {
+		if rule.Key != "" && rule.Key == key {
+			return rule.Action, true
+		}
+		if rule.KeyGlob != "" {
+			if ok, _ := path.Match(rule.KeyGlob, key); ok {
+				return rule.Action, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// redactString applies the value rules to s. It reports false if the value
+// must be dropped.
+func (r *redactor) redactString(s string) (string, bool) {
+	for _, rule := range r.rules {
+		if rule.Value == nil || !rule.Value.MatchString(s) {
+			continue
+		}
+		switch rule.Action {
+		case RedactDrop:
+			return "", false
+		case RedactHash:
+			s = rule.Value.ReplaceAllStringFunc(s, hashValue)
+		default:
+			s = rule.Value.ReplaceAllLiteralString(s, RedactedValue)
+		}
+	}
+	return s, true
+}
+
+// replacement returns what a value matched by a key rule is replaced with.
+func replacement(act RedactAction, v interface{}) string {
+	if act == RedactHash {
+		return hashValue(fmt.Sprint(v))
+	}
+	return RedactedValue
+}
+
+func hashValue(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+func (r *redactor) redactFields(fields []Field) []Field {
+	if len(fields) == 0 {
+		return fields
+	}
+	redacted := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if f, ok := r.redactField(f); ok {
+			redacted = append(redacted, f)
+		}
+	}
+	return redacted
+}
+
+// redactField returns the redacted field, or false if it must be dropped.
+func (r *redactor) redactField(f Field) (Field, bool) {
+	switch f.Type {
+	case NamespaceType, SkipType:
+		return f, true
+	}
+
+	if act, ok := r.matchKey(f.Key); ok {
+		if act == RedactDrop {
+			return f, false
+		}
+		var v interface{} = RedactedValue
+		if act == RedactHash {
+			enc := NewMapObjectEncoder()
+			f.AddTo(enc)
+			v = enc.Fields[f.Key]
+		}
+		return Field{Key: f.Key, Type: StringType, String: replacement(act, v)}, true
+	}
+
+	switch f.Type {
+	case StringType:
+		s, ok := r.redactString(f.String)
+		f.String = s
+		return f, ok
+	case ByteStringType:
+		s, ok := r.redactString(string(f.Interface.([]byte)))
+		return Field{Key: f.Key, Type: StringType, String: s}, ok
+	case StringerType:
+		str, ok := stringOf(f)
+		if !ok {
+			// Leave the panic for the encoder to report.
+			return f, true
+		}
+		s, ok := r.redactString(str)
+		return Field{Key: f.Key, Type: StringType, String: s}, ok
+	case ErrorType:
+		// Errors are only rewritten if their message needs redacting, since
+		// that loses any verbose output the encoder would otherwise add.
+		msg, ok := stringOf(f)
+		if !ok {
+			return f, true
+		}
+		s, ok := r.redactString(msg)
+		if !ok || s == msg {
+			return f, ok
+		}
+		return Field{Key: f.Key, Type: StringType, String: s}, true
+	case ObjectMarshalerType:
+		f.Interface = redactedObject{r, f.Interface.(ObjectMarshaler)}
+	case ArrayMarshalerType:
+		f.Interface = redactedArray{r, f.Interface.(ArrayMarshaler)}
+	case ReflectType:
+		f.Interface = r.redactReflected(f.Interface)
+	}
+	return f, true
+}
+
+// redactReflected round-trips obj through encoding/json so that rules can be
+// applied to the keys and values the JSON encoder would emit. If no rule
+// applies, or obj can't be marshaled, it's returned unchanged and encoded as
+// usual.
+func (r *redactor) redactReflected(obj interface{}) (redacted interface{}) {
+	defer func() {
+		if recover() != nil {
+			redacted = obj
+		}
+	}()
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return obj
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// Keep numbers as they were, rather than rounding them to float64.
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return obj
+	}
+	var changed bool
+	v, _ = r.redactJSON(v, &changed)
+	if !changed {
+		return obj
+	}
+	return v
+}
+
+// redactJSON applies the rules to a decoded JSON value, setting changed if
+// any of them fired. It reports false if the value must be dropped.
+func (r *redactor) redactJSON(v interface{}, changed *bool) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		s, ok := r.redactString(v)
+		if !ok || s != v {
+			*changed = true
+		}
+		return s, ok
+	case map[string]interface{}:
+		for k, elem := range v {
+			if act, ok := r.matchKey(k); ok {
+				*changed = true
+				if act == RedactDrop {
+					delete(v, k)
+				} else {
+					v[k] = replacement(act, elem)
+				}
+				continue
+			}
+			if elem, ok := r.redactJSON(elem, changed); ok {
+				v[k] = elem
+			} else {
+				delete(v, k)
+			}
+		}
+	case []interface{}:
+		elems := v[:0]
+		for _, elem := range v {
+			if elem, ok := r.redactJSON(elem, changed); ok {
+				elems = append(elems, elem)
+			}
+		}
+		return elems, true
+	}
+	return v, true
+}
+
+type redactedObject struct {
+	r *redactor
+	m ObjectMarshaler
+}
+
+func (o redactedObject) MarshalLogObject(enc ObjectEncoder) error {
+	return o.m.MarshalLogObject(&redactObjectEncoder{r: o.r, enc: enc})
+}
+
+type redactedArray struct {
+	r *redactor
+	m ArrayMarshaler
+}
+
+func (a redactedArray) MarshalLogArray(enc ArrayEncoder) error {
+	return a.m.MarshalLogArray(&redactArrayEncoder{ArrayEncoder: enc, r: a.r})
+}
+
+// redactObjectEncoder applies the rules to everything a user-supplied
+// ObjectMarshaler adds.
+type redactObjectEncoder struct {
+	r   *redactor
+	enc ObjectEncoder
+}
+
+// redacted handles a key matched by a key rule and reports whether it did.
+func (e *redactObjectEncoder) redacted(key string, v func() interface{}) bool {
+	act, ok := e.r.matchKey(key)
+	if !ok {
+		return false
+	}
+	if act != RedactDrop {
+		e.enc.AddString(key, replacement(act, v()))
+	}
+	return true
+}
+
+func (e *redactObjectEncoder) AddArray(key string, v ArrayMarshaler) error {
+	if e.redacted(key, func() interface{} { return encodeArray(v) }) {
+		return nil
+	}
+	return e.enc.AddArray(key, redactedArray{e.r, v})
+}
+
+func (e *redactObjectEncoder) AddObject(key string, v ObjectMarshaler) error {
+	if e.redacted(key, func() interface{} { return encodeObject(v) }) {
+		return nil
+	}
+	return e.enc.AddObject(key, redactedObject{e.r, v})
+}
+
+func (e *redactObjectEncoder) AddBinary(key string, v []byte) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddBinary(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddByteString(key string, v []byte) {
+	if e.redacted(key, func() interface{} { return string(v) }) {
+		return
+	}
+	if s, ok := e.r.redactString(string(v)); ok {
+		e.enc.AddString(key, s)
+	}
+}
+
+func (e *redactObjectEncoder) AddBool(key string, v bool) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddBool(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex128(key string, v complex128) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddComplex128(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddComplex64(key string, v complex64) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddComplex64(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddDuration(key string, v time.Duration) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddDuration(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat64(key string, v float64) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddFloat64(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddFloat32(key string, v float32) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddFloat32(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt(key string, v int) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddInt(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt64(key string, v int64) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddInt64(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt32(key string, v int32) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddInt32(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt16(key string, v int16) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddInt16(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddInt8(key string, v int8) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddInt8(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddString(key, v string) {
+	if e.redacted(key, func() interface{} { return v }) {
+		return
+	}
+	if s, ok := e.r.redactString(v); ok {
+		e.enc.AddString(key, s)
+	}
+}
+
+func (e *redactObjectEncoder) AddTime(key string, v time.Time) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddTime(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint(key string, v uint) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUint(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint64(key string, v uint64) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUint64(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint32(key string, v uint32) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUint32(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint16(key string, v uint16) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUint16(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUint8(key string, v uint8) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUint8(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddUintptr(key string, v uintptr) {
+	if !e.redacted(key, func() interface{} { return v }) {
+		e.enc.AddUintptr(key, v)
+	}
+}
+
+func (e *redactObjectEncoder) AddReflected(key string, v interface{}) error {
+	if e.redacted(key, func() interface{} { return v }) {
+		return nil
+	}
+	return e.enc.AddReflected(key, e.r.redactReflected(v))
+}
+
+func (e *redactObjectEncoder) OpenNamespace(key string) {
+	e.enc.OpenNamespace(key)
+}
+
+// redactArrayEncoder applies the value rules to everything a user-supplied
+// ArrayMarshaler appends. Array elements have no keys, so key rules only
+// apply to the objects nested inside them.
+type redactArrayEncoder struct {
+	ArrayEncoder
+	r *redactor
+}
+
+func (e *redactArrayEncoder) AppendByteString(v []byte) {
+	if s, ok := e.r.redactString(string(v)); ok {
+		e.ArrayEncoder.AppendString(s)
+	}
+}
+
+func (e *redactArrayEncoder) AppendString(v string) {
+	if s, ok := e.r.redactString(v); ok {
+		e.ArrayEncoder.AppendString(s)
+	}
+}
+
+func (e *redactArrayEncoder) AppendArray(v ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactedArray{e.r, v})
+}
+
+func (e *redactArrayEncoder) AppendObject(v ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactedObject{e.r, v})
+}
+
+func (e *redactArrayEncoder) AppendReflected(v interface{}) error {
+	return e.ArrayEncoder.AppendReflected(e.r.redactReflected(v))
+}
+
+// encodeObject and encodeArray materialize marshalers so that their content
+// can be hashed.
+func encodeObject(v ObjectMarshaler) interface{} {
+	enc := NewMapObjectEncoder()
+	v.MarshalLogObject(enc)
+	return enc.Fields
+}
+
+func encodeArray(v ArrayMarshaler) interface{} {
+	enc := NewMapObjectEncoder()
+	enc.AddArray("", v)
+	return enc.Fields[""]
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _emailRegexp = regexp.MustCompile(`[a-z]+@example\.com`)
+
+type redactRequest struct {
+	User     string            `json:"user"`
+	Token    string            `json:"token"`
+	Email    string            `json:"email"`
+	Headers  map[string]string `json:"headers"`
+	Internal string            `json:"-"`
+}
+
+type redactStringer string
+
+func (s redactStringer) String() string { return string(s) }
+
+func withRedactCore(t *testing.T, rules []RedactRule, f func(Core, *bytes.Buffer)) {
+	buf := &bytes.Buffer{}
+	core := NewCore(
+		NewJSONEncoder(EncoderConfig{MessageKey: "msg"}),
+		AddSync(buf),
+		DebugLevel,
+	)
+	rc, err := NewRedactCore(core, rules...)
+	require.NoError(t, err, "Unexpected error constructing redact core.")
+	f(rc, buf)
+}
+
+func writeRedacted(core Core, fields ...Field) {
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, fields...)
+}
+
+func TestRedactCoreFields(t *testing.T) {
+	rules := []RedactRule{
+		{Key: "password"},
+		{KeyGlob: "*_token", Action: RedactHash},
+		{Key: "secret", Action: RedactDrop},
+		{Value: _emailRegexp},
+		{Value: regexp.MustCompile(`^card:`), Action: RedactDrop},
+	}
+
+	tests := []struct {
+		desc   string
+		fields []Field
+		want   string
+	}{
+		{
+			desc:   "key mask",
+			fields: []Field{{Key: "password", Type: StringType, String: "hunter2"}},
+			want:   `{"msg":"m","password":"[REDACTED]"}`,
+		},
+		{
+			desc:   "key mask on non-string",
+			fields: []Field{makeInt64Field("password", 1234)},
+			want:   `{"msg":"m","password":"[REDACTED]"}`,
+		},
+		{
+			desc:   "key glob hash",
+			fields: []Field{{Key: "api_token", Type: StringType, String: "abc"}},
+			want:   `{"msg":"m","api_token":"sha256:ba7816bf8f01cfea"}`,
+		},
+		{
+			desc:   "key drop",
+			fields: []Field{{Key: "secret", Type: StringType, String: "s"}, makeInt64Field("n", 1)},
+			want:   `{"msg":"m","n":1}`,
+		},
+		{
+			desc:   "value mask",
+			fields: []Field{{Key: "to", Type: StringType, String: "mail bob@example.com now"}},
+			want:   `{"msg":"m","to":"mail [REDACTED] now"}`,
+		},
+		{
+			desc:   "value drop",
+			fields: []Field{{Key: "pan", Type: StringType, String: "card:4111"}},
+			want:   `{"msg":"m"}`,
+		},
+		{
+			desc:   "byte string",
+			fields: []Field{{Key: "to", Type: ByteStringType, Interface: []byte("bob@example.com")}},
+			want:   `{"msg":"m","to":"[REDACTED]"}`,
+		},
+		{
+			desc:   "stringer",
+			fields: []Field{{Key: "to", Type: StringerType, Interface: redactStringer("bob@example.com")}},
+			want:   `{"msg":"m","to":"[REDACTED]"}`,
+		},
+		{
+			desc:   "panicking stringer",
+			fields: []Field{{Key: "to", Type: StringerType, Interface: panicking{}}},
+			want:   `{"msg":"m","toError":"PANIC=runtime error: invalid memory address or nil pointer dereference"}`,
+		},
+		{
+			desc:   "error",
+			fields: []Field{{Key: "error", Type: ErrorType, Interface: errors.New("no user bob@example.com")}},
+			want:   `{"msg":"m","error":"no user [REDACTED]"}`,
+		},
+		{
+			desc:   "untouched",
+			fields: []Field{{Key: "user", Type: StringType, String: "bob"}, makeInt64Field("n", 1)},
+			want:   `{"msg":"m","user":"bob","n":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		withRedactCore(t, rules, func(core Core, buf *bytes.Buffer) {
+			writeRedacted(core, tt.fields...)
+			assert.Equal(t, tt.want, strings.TrimSpace(buf.String()), "Unexpected output for %s.", tt.desc)
+		})
+	}
+}
+
+func TestRedactCoreWith(t *testing.T) {
+	withRedactCore(t, []RedactRule{{Key: "password"}, {Value: _emailRegexp}}, func(core Core, buf *bytes.Buffer) {
+		core = core.With([]Field{
+			{Key: "password", Type: StringType, String: "hunter2"},
+			{Key: "user", Type: StringType, String: "bob@example.com"},
+		})
+		writeRedacted(core, Field{Key: "password", Type: StringType, String: "hunter3"})
+		assert.Equal(
+			t,
+			`{"msg":"m","password":"[REDACTED]","user":"[REDACTED]","password":"[REDACTED]"}`,
+			strings.TrimSpace(buf.String()),
+			"Expected context and log-site fields to be redacted.",
+		)
+	})
+}
+
+func TestRedactCoreMarshalers(t *testing.T) {
+	rules := []RedactRule{{Key: "token"}, {Key: "drop", Action: RedactDrop}, {Value: _emailRegexp}}
+	withRedactCore(t, rules, func(core Core, buf *bytes.Buffer) {
+		obj := ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+			enc.AddString("user", "bob")
+			enc.AddString("token", "t0k3n")
+			enc.AddInt("drop", 1)
+			enc.AddString("email", "bob@example.com")
+			return enc.AddObject("nested", ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+				enc.AddBool("token", true)
+				return nil
+			}))
+		})
+		arr := ArrayMarshalerFunc(func(enc ArrayEncoder) error {
+			enc.AppendString("bob@example.com")
+			enc.AppendInt(42)
+			return enc.AppendObject(ObjectMarshalerFunc(func(enc ObjectEncoder) error {
+				enc.AddString("token", "t0k3n")
+				return nil
+			}))
+		})
+		writeRedacted(
+			core,
+			Field{Key: "obj", Type: ObjectMarshalerType, Interface: obj},
+			Field{Key: "arr", Type: ArrayMarshalerType, Interface: arr},
+		)
+		assert.Equal(
+			t,
+			`{"msg":"m","obj":{"user":"bob","token":"[REDACTED]","email":"[REDACTED]","nested":{"token":"[REDACTED]"}},`+
+				`"arr":["[REDACTED]",42,{"token":"[REDACTED]"}]}`,
+			strings.TrimSpace(buf.String()),
+			"Expected marshaler output to be redacted.",
+		)
+	})
+}
+
+func TestRedactCoreReflected(t *testing.T) {
+	rules := []RedactRule{{Key: "token"}, {KeyGlob: "X-*", Action: RedactDrop}, {Value: _emailRegexp, Action: RedactHash}}
+	withRedactCore(t, rules, func(core Core, buf *bytes.Buffer) {
+		req := redactRequest{
+			User:     "bob",
+			Token:    "t0k3n",
+			Email:    "bob@example.com",
+			Headers:  map[string]string{"X-Api-Key": "k", "Accept": "*/*"},
+			Internal: "hidden",
+		}
+		writeRedacted(core, Field{Key: "req", Type: ReflectType, Interface: req})
+		assert.Equal(
+			t,
+			`{"msg":"m","req":{"email":"sha256:5ff860bf1190596c","headers":{"Accept":"*/*"},"token":"[REDACTED]","user":"bob"}}`,
+			strings.TrimSpace(buf.String()),
+			"Expected reflected struct to be redacted.",
+		)
+		assert.Equal(t, "t0k3n", req.Token, "Expected the logged value to be left untouched.")
+	})
+}
+
+func TestRedactCoreReflectedNumbers(t *testing.T) {
+	type account struct {
+		Zeta  int64
+		Alpha string
+		Token string `json:",omitempty"`
+	}
+	withRedactCore(t, []RedactRule{{Key: "Token"}}, func(core Core, buf *bytes.Buffer) {
+		writeRedacted(core, Field{Key: "s", Type: ReflectType, Interface: account{Zeta: 9007199254740993, Alpha: "a"}})
+		writeRedacted(core, Field{Key: "s", Type: ReflectType, Interface: account{Zeta: 9007199254740993, Token: "t"}})
+		assert.Equal(
+			t,
+			`{"msg":"m","s":{"Zeta":9007199254740993,"Alpha":"a"}}`+"\n"+
+				`{"msg":"m","s":{"Alpha":"","Token":"[REDACTED]","Zeta":9007199254740993}}`,
+			strings.TrimSpace(buf.String()),
+			"Expected untouched values to keep their field order, and numbers to keep their precision.",
+		)
+	})
+}
+
+func TestRedactCoreLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := NewCore(NewJSONEncoder(EncoderConfig{MessageKey: "msg"}), AddSync(buf), WarnLevel)
+	rc, err := NewRedactCore(core, RedactRule{Key: "password"})
+	require.NoError(t, err, "Unexpected error constructing redact core.")
+
+	assert.False(t, rc.Enabled(InfoLevel), "Expected redact core to respect the wrapped core's level.")
+	assert.Nil(t, rc.Check(Entry{Level: InfoLevel}, nil), "Expected disabled entries to be dropped.")
+	assert.NotNil(t, rc.Check(Entry{Level: WarnLevel}, nil), "Expected enabled entries to be checked.")
+}
+
+func TestRedactCoreRespectsWrappedCheck(t *testing.T) {
+	info, infoLogs := observer.New(InfoLevel)
+	errs, errLogs := observer.New(ErrorLevel)
+	core, err := NewRedactCore(NewTee(info, errs), RedactRule{Key: "password"})
+	require.NoError(t, err, "Unexpected error constructing redact core.")
+
+	checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"}, Field{Key: "password", Type: StringType, String: "hunter2"})
+	require.Equal(t, 1, infoLogs.Len(), "Expected the entry on the enabled Core.")
+	assert.Equal(t, "[REDACTED]", infoLogs.All()[0].ContextMap()["password"], "Expected the entry to be redacted.")
+	assert.Equal(t, 0, errLogs.Len(), "Expected nothing on the Core which doesn't log InfoLevel.")
+}
+
+func TestRedactCoreRespectsWrappedSampler(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core, err := NewRedactCore(NewSampler(obs, time.Minute, 2, 0), RedactRule{Key: "password"})
+	require.NoError(t, err, "Unexpected error constructing redact core.")
+
+	for i := 0; i < 5; i++ {
+		checkAndWrite(core, Entry{Level: InfoLevel, Message: "m"})
+	}
+	assert.Equal(t, 2, logs.Len(), "Expected the wrapped sampler to drop entries.")
+}
+
+func TestRedactCoreInvalidRules(t *testing.T) {
+	tests := []struct {
+		rule RedactRule
+		want string
+	}{
+		{RedactRule{}, "must set Key, KeyGlob or Value"},
+		{RedactRule{KeyGlob: "[a-"}, `invalid redact key glob "[a-"`},
+	}
+	for _, tt := range tests {
+		_, err := NewRedactCore(NewNopCore(), tt.rule)
+		require.Error(t, err, "Expected an error for rule %+v.", tt.rule)
+		assert.Contains(t, err.Error(), tt.want, "Unexpected error for rule %+v.", tt.rule)
+	}
+}
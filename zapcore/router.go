@@ -0,0 +1,180 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+// A Route sends the entries it matches to a Core. Each criterion left unset
+// matches every entry.
+type Route struct {
+	// Levels restricts the route to the levels it enables.
+	Levels LevelEnabler
+	// NamePrefix restricts the route to loggers whose name starts with it.
+	NamePrefix string
+	// FieldKey and FieldValue restrict the route to entries with a field
+	// named FieldKey whose value formats as FieldValue. Fields added at the
+	// log site take precedence over those added with With.
+	FieldKey   string
+	FieldValue string
+
+	Core Core
+}
+
+// routeState is a Route along with its Core and the value of its field, if
+// it was added with With.
+type routeState struct {
+	*Route
+	core     Core
+	value    string
+	hasValue bool
+}
+
+func (r *routeState) matchLevelAndName(ent Entry) bool {
+	if r.Levels != nil && !r.Levels.Enabled(ent.Level) {
+		return false
+	}
+	return strings.HasPrefix(ent.LoggerName, r.NamePrefix)
+}
+
+type routerCore struct {
+	routes []routeState
+	def    Core
+}
+
+// NewRouterCore creates a Core that sends each entry to the Core of the
+// first Route matching it, or to def if none do. A nil def discards entries
+// which no Route matches.
+//
+// Entries which can only be routed once their fields are known are written
+// to the chosen Core directly, skipping its Check method. Route Cores which
+// filter entries by more than level, such as samplers, should only be used
+// in routes without field criteria.
+func NewRouterCore(def Core, routes ...Route) Core {
+	if def == nil {
+		def = NewNopCore()
+	}
+	states := make([]routeState, len(routes))
+	for i := range routes {
+		r := routes[i]
+		states[i] = routeState{Route: &r, core: r.Core}
+	}
+	return &routerCore{routes: states, def: def}
+}
+
+func (c *routerCore) Enabled(lvl Level) bool {
+	for _, r := range c.routes {
+		if (r.Levels == nil || r.Levels.Enabled(lvl)) && r.core.Enabled(lvl) {
+			return true
+		}
+	}
+	return c.def.Enabled(lvl)
+}
+
+func (c *routerCore) With(fields []Field) Core {
+	clone := &routerCore{
+		routes: make([]routeState, len(c.routes)),
+		def:    c.def.With(fields),
+	}
+	for i, r := range c.routes {
+		r.core = r.core.With(fields)
+		if r.FieldKey != "" {
+			if v, ok := fieldValue(fields, r.FieldKey); ok {
+				r.value, r.hasValue = v, true
+			}
+		}
+		clone.routes[i] = r
+	}
+	return clone
+}
+
+func (c *routerCore) Check(ent Entry, ce *CheckedEntry) *CheckedEntry {
+	for i := range c.routes {
+		r := &c.routes[i]
+		if !r.matchLevelAndName(ent) {
+			continue
+		}
+		if r.FieldKey == "" {
+			return r.core.Check(ent, ce)
+		}
+		// Defer routing to Write, where the fields are known.
+		if c.Enabled(ent.Level) {
+			return ce.AddCore(ent, c)
+		}
+		return ce
+	}
+	return c.def.Check(ent, ce)
+}
+
+func (c *routerCore) Write(ent Entry, fields []Field) error {
+	core := c.route(ent, fields)
+	if !core.Enabled(ent.Level) {
+		return nil
+	}
+	return core.Write(ent, fields)
+}
+
+func (c *routerCore) route(ent Entry, fields []Field) Core {
+	for i := range c.routes {
+		r := &c.routes[i]
+		if !r.matchLevelAndName(ent) {
+			continue
+		}
+		if r.FieldKey == "" {
+			return r.core
+		}
+		value, ok := r.value, r.hasValue
+		if v, found := fieldValue(fields, r.FieldKey); found {
+			value, ok = v, true
+		}
+		if ok && value == r.FieldValue {
+			return r.core
+		}
+	}
+	return c.def
+}
+
+func (c *routerCore) Sync() error {
+	err := c.def.Sync()
+	for _, r := range c.routes {
+		err = multierr.Append(err, r.core.Sync())
+	}
+	return err
+}
+
+func (c *routerCore) ReOpen() error {
+	err := c.def.ReOpen()
+	for _, r := range c.routes {
+		err = multierr.Append(err, r.core.ReOpen())
+	}
+	return err
+}
+
+func (c *routerCore) Close() error {
+	err := c.def.Close()
+	for _, r := range c.routes {
+		err = multierr.Append(err, r.core.Close())
+	}
+	return err
+}
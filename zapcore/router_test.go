@@ -0,0 +1,129 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/templexxx/zap/zapcore"
+	"github.com/templexxx/zap/zaptest/observer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type routerLogs struct {
+	audit, db, errs, def *observer.ObservedLogs
+}
+
+func newTestRouter() (Core, routerLogs) {
+	audit, auditLogs := observer.New(DebugLevel)
+	db, dbLogs := observer.New(DebugLevel)
+	errs, errLogs := observer.New(DebugLevel)
+	def, defLogs := observer.New(InfoLevel)
+	core := NewRouterCore(def,
+		Route{FieldKey: "audit", FieldValue: "true", Core: audit},
+		Route{NamePrefix: "db", Core: db},
+		Route{Levels: ErrorLevel, Core: errs},
+	)
+	return core, routerLogs{auditLogs, dbLogs, errLogs, defLogs}
+}
+
+func routed(core Core, name string, lvl Level, fields ...Field) {
+	checkAndWrite(core, Entry{LoggerName: name, Level: lvl, Message: "m"}, fields...)
+}
+
+func TestRouterCore(t *testing.T) {
+	auditField := Field{Key: "audit", Type: BoolType, Integer: 1}
+	tests := []struct {
+		desc   string
+		name   string
+		lvl    Level
+		fields []Field
+		want   func(routerLogs) *observer.ObservedLogs
+	}{
+		{"field", "db.query", InfoLevel, []Field{auditField}, func(l routerLogs) *observer.ObservedLogs { return l.audit }},
+		{"field mismatch", "", InfoLevel, []Field{{Key: "audit", Type: BoolType}}, func(l routerLogs) *observer.ObservedLogs { return l.def }},
+		{"name prefix", "db.query", DebugLevel, nil, func(l routerLogs) *observer.ObservedLogs { return l.db }},
+		{"level", "http", ErrorLevel, nil, func(l routerLogs) *observer.ObservedLogs { return l.errs }},
+		{"default", "http", InfoLevel, nil, func(l routerLogs) *observer.ObservedLogs { return l.def }},
+		{"default disabled", "http", DebugLevel, nil, func(routerLogs) *observer.ObservedLogs { return nil }},
+		{"deferred default disabled", "", DebugLevel, []Field{{Key: "audit", Type: BoolType}}, func(routerLogs) *observer.ObservedLogs { return nil }},
+	}
+
+	for _, tt := range tests {
+		core, logs := newTestRouter()
+		routed(core, tt.name, tt.lvl, tt.fields...)
+		want := tt.want(logs)
+		for _, l := range []*observer.ObservedLogs{logs.audit, logs.db, logs.errs, logs.def} {
+			if l == want {
+				assert.Equal(t, 1, l.Len(), "Expected entry on the matching route for %s.", tt.desc)
+			} else {
+				assert.Equal(t, 0, l.Len(), "Unexpected entry on another route for %s.", tt.desc)
+			}
+		}
+	}
+}
+
+func TestRouterCoreWith(t *testing.T) {
+	core, logs := newTestRouter()
+	auditCore := core.With([]Field{{Key: "audit", Type: BoolType, Integer: 1}, makeInt64Field("k", 1)})
+
+	routed(auditCore, "http", InfoLevel)
+	routed(auditCore, "http", InfoLevel, Field{Key: "audit", Type: BoolType})
+	assert.Equal(t, 1, logs.audit.Len(), "Expected context fields to be routed on.")
+	assert.Equal(t, 1, logs.def.Len(), "Expected log-site fields to take precedence.")
+	assert.Equal(t, []Field{
+		{Key: "audit", Type: BoolType, Integer: 1},
+		makeInt64Field("k", 1),
+	}, logs.audit.All()[0].Context, "Expected route Cores to receive the context.")
+}
+
+func TestRouterCoreEnabled(t *testing.T) {
+	obs, _ := observer.New(WarnLevel)
+	def, _ := observer.New(ErrorLevel)
+	core := NewRouterCore(def, Route{Levels: InfoLevel, NamePrefix: "x", Core: obs})
+
+	assert.False(t, core.Enabled(InfoLevel), "Expected routes to be enabled only where their Cores are.")
+	assert.True(t, core.Enabled(WarnLevel), "Expected an enabled route to enable the router.")
+	assert.True(t, core.Enabled(ErrorLevel), "Expected the default route to enable the router.")
+}
+
+func TestRouterCoreNilDefault(t *testing.T) {
+	obs, logs := observer.New(DebugLevel)
+	core := NewRouterCore(nil, Route{NamePrefix: "db", Core: obs})
+
+	assert.True(t, core.Enabled(DebugLevel), "Expected the route to enable the router.")
+	routed(core, "db", InfoLevel)
+	routed(core, "http", InfoLevel)
+	routed(core.With([]Field{makeInt64Field("k", 1)}), "http", InfoLevel)
+	assert.Equal(t, 1, logs.Len(), "Expected unmatched entries to be discarded.")
+	assert.NoError(t, core.Sync(), "Unexpected error syncing.")
+}
+
+func TestRouterCoreSyncAndReOpen(t *testing.T) {
+	err := errors.New("failed")
+	ok, _ := observer.New(DebugLevel)
+	core := NewRouterCore(failingCore{err: err}, Route{Core: ok}, Route{Core: failingCore{err: err}})
+
+	assert.Equal(t, "failed; failed", core.Sync().Error(), "Expected Sync errors to be combined.")
+	assert.Equal(t, "failed; failed", core.ReOpen().Error(), "Expected ReOpen errors to be combined.")
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renderPath expands the strftime-style verbs in pattern for t. Unknown
+// verbs are kept as they are.
+func renderPath(pattern string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(strconv.Itoa(t.Year()))
+		case 'm':
+			writeTwoDigits(&b, int(t.Month()))
+		case 'd':
+			writeTwoDigits(&b, t.Day())
+		case 'H':
+			writeTwoDigits(&b, t.Hour())
+		case 'M':
+			writeTwoDigits(&b, t.Minute())
+		case 'S':
+			writeTwoDigits(&b, t.Second())
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(pattern[i])
+		}
+	}
+	return b.String()
+}
+
+// patternInterval returns the interval at which the rendering of pattern
+// changes, as closely as a RotateInterval can tell.
+func patternInterval(pattern string) RotateInterval {
+	interval := RotateNever
+	for i := 0; i+1 < len(pattern); i++ {
+		if pattern[i] != '%' {
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'H', 'M', 'S':
+			return RotateHourly
+		case 'Y', 'm', 'd':
+			interval = RotateDaily
+		}
+	}
+	return interval
+}
+
+// patternGlob returns a filepath.Match pattern matching every rendering of
+// pattern, and nothing else: each verb matches as many digits as it renders
+// to, and the rest of pattern matches literally.
+func patternGlob(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' || i+1 == len(pattern) {
+			writeGlobLiteral(&b, pattern[i])
+			continue
+		}
+		i++
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(strings.Repeat(_digitGlob, 4))
+		case 'm', 'd', 'H', 'M', 'S':
+			b.WriteString(_digitGlob + _digitGlob)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			writeGlobLiteral(&b, pattern[i])
+		}
+	}
+	return b.String()
+}
+
+// timeFormatGlob returns a filepath.Match pattern matching the times
+// formatted with layout, which must be purely numeric like backupTimeFormat.
+func timeFormatGlob(layout string) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if c := layout[i]; c >= '0' && c <= '9' {
+			b.WriteString(_digitGlob)
+		} else {
+			writeGlobLiteral(&b, c)
+		}
+	}
+	return b.String()
+}
+
+const _digitGlob = "[0-9]"
+
+func writeGlobLiteral(b *strings.Builder, c byte) {
+	switch c {
+	case '*', '?', '[', '\\':
+		b.WriteByte('\\')
+	}
+	b.WriteByte(c)
+}
+
+func writeTwoDigits(b *strings.Builder, n int) {
+	b.WriteByte(byte('0' + n/10))
+	b.WriteByte(byte('0' + n%10))
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package zapcore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPath(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		pattern, want, glob string
+	}{
+		{"app.log", "app.log", "app.log"},
+		{"app-%Y%m%d.log", "app-20260102.log", "app-[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9].log"},
+		{"%H%M%S", "030405", "[0-9][0-9][0-9][0-9][0-9][0-9]"},
+		{"[x]*?-%d", "[x]*?-02", `\[x]\*\?-[0-9][0-9]`},
+		{"100%%-%q-%", "100%-%q-%", "100%-%q-%"},
+	}
+	for _, tt := range tests {
+		rendered := renderPath(tt.pattern, ts)
+		assert.Equal(t, tt.want, rendered, "Unexpected rendering of %q.", tt.pattern)
+		glob := patternGlob(tt.pattern)
+		assert.Equal(t, tt.glob, glob, "Unexpected glob for %q.", tt.pattern)
+		ok, err := filepath.Match(glob, rendered)
+		assert.True(t, ok && err == nil, "Expected the glob for %q to match its rendering.", tt.pattern)
+	}
+}
+
+func TestPatternGlobRejectsOtherFiles(t *testing.T) {
+	for _, name := range []string{"x.log", "important.log", "other-app.log", "2026.log", "202601021.log"} {
+		ok, err := filepath.Match(patternGlob("%Y%m%d.log"), name)
+		require.NoError(t, err, "Unexpected error matching %q.", name)
+		assert.False(t, ok, "Expected %q not to match the pattern.", name)
+	}
+	ok, _ := filepath.Match(timeFormatGlob(backupTimeFormat), "2026-01-02T03-04-05.000")
+	assert.True(t, ok, "Expected the backup time glob to match backup timestamps.")
+}
+
+func TestPatternInterval(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    RotateInterval
+	}{
+		{"app.log", RotateNever},
+		{"100%%.log", RotateNever},
+		{"app-%Y%m.log", RotateDaily},
+		{"app-%Y%m%d.log", RotateDaily},
+		{"app-%Y%m%d-%H.log", RotateHourly},
+		{"%M-%Y.log", RotateHourly},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, patternInterval(tt.pattern), "Unexpected interval for %q.", tt.pattern)
+	}
+}
@@ -21,21 +21,29 @@
 package zapcore
 
 import (
+	"errors"
 	"io"
+	"os"
 	"sync"
 )
 
-// A WriteSyncer is an io.Writer that can also flush any buffered data. Note
-// that *os.File (and thus, os.Stderr and os.Stdout) implement WriteSyncer.
+// errClosed is returned by writes after Close.
+var errClosed = errors.New("write after Close")
+
+// A WriteSyncer is an io.Writer that can also flush any buffered data, reopen
+// its destination and release it with Close.
 type WriteSyncer interface {
 	io.Writer
 	Sync() error
 	ReOpen() error
+	Close() error
 }
 
 // AddSync converts an io.Writer to a WriteSyncer. It attempts to be
 // intelligent: if the concrete type of the io.Writer implements WriteSyncer,
-// we'll use the existing Sync method. If it doesn't, we'll add a no-op Sync.
+// we'll use the existing Sync method. If it doesn't, we'll add a no-op Sync,
+// and a Close which closes the io.Writer if it's an io.Closer, other than
+// os.Stdout and os.Stderr.
 func AddSync(w io.Writer) WriteSyncer {
 	switch w := w.(type) {
 	case WriteSyncer:
@@ -57,13 +65,26 @@ func (w writerWrapper) ReOpen() error {
 	return nil
 }
 
+// Close leaves the standard streams open for the rest of the process.
+func (w writerWrapper) Close() error {
+	if w.Writer == os.Stdout || w.Writer == os.Stderr {
+		return nil
+	}
+	if c, ok := w.Writer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 type lockedWriteSyncer struct {
 	sync.Mutex
-	ws WriteSyncer
+	ws     WriteSyncer
+	closed bool
 }
 
 // Lock wraps a WriteSyncer in a mutex to make it safe for concurrent use. In
-// particular, *os.Files must be locked before use.
+// particular, *os.Files must be locked before use. The wrapped WriteSyncer is
+// closed only once, however many times Close is called.
 func Lock(ws WriteSyncer) WriteSyncer {
 	if _, ok := ws.(*lockedWriteSyncer); ok {
 		// no need to layer on another lock
@@ -92,3 +113,13 @@ func (s *lockedWriteSyncer) ReOpen() error {
 	s.Unlock()
 	return err
 }
+
+func (s *lockedWriteSyncer) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.ws.Close()
+}
@@ -2,81 +2,555 @@ package zapcore
 
 import (
 	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"go.uber.org/multierr"
+)
+
+// backupTimeFormat is the format of the timestamps in rotated file names. It
+// sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// A RotateInterval is a period after which a file is rotated, regardless of
+// its size. Periods start on the boundaries of the local clock.
+type RotateInterval uint8
+
+const (
+	// RotateNever disables time-based rotation.
+	RotateNever RotateInterval = iota
+	// RotateHourly rotates at the start of every hour.
+	RotateHourly
+	// RotateDaily rotates at midnight.
+	RotateDaily
 )
 
+// next returns the start of the period following the one t is in.
+func (i RotateInterval) next(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch i {
+	case RotateHourly:
+		return time.Date(y, m, d, t.Hour()+1, 0, 0, 0, t.Location())
+	case RotateDaily:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// RotateConfig configures the rotation of the file written by
+// RotatingBuffer.
+type RotateConfig struct {
+	// MaxSize is the size in bytes the file may grow to before it's rotated.
+	// Zero disables size-based rotation.
+	MaxSize int64
+	// Interval rotates the file periodically. If the output path is a pattern
+	// and Interval is RotateNever, it's inferred from the pattern's finest
+	// verb: hourly for %H, %M and %S, daily otherwise.
+	Interval RotateInterval
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of
+	// them.
+	MaxBackups int
+	// MaxAge is how long rotated files are kept, judging by the timestamps in
+	// their names (or their modification times, if the output path is a
+	// pattern). Zero keeps them regardless of age.
+	MaxAge time.Duration
+	// Clock decides when time-based rotation happens, and the time output
+	// path patterns are rendered for. It defaults to DefaultClock; it should
+	// be the Logger's clock, so that files are named after the entries in
+	// them, and tests may replace both to rotate without waiting.
+	Clock Clock
+	// Compress gzips each rotated file to <name>.gz in the background, and
+	// removes the original once the compressed copy is on disk.
+	Compress bool
+	// ErrorHandler is called with the errors of background work: rotating at
+	// the end of a period, and compressing and removing rotated files.
+	// Errors are dropped if it's nil.
+	ErrorHandler func(error)
+}
+
+func (rc RotateConfig) handleError(err error) {
+	if err != nil && rc.ErrorHandler != nil {
+		rc.ErrorHandler(err)
+	}
+}
+
 // log with bufio
 type bufWriterSync struct {
+	mu   sync.Mutex // need lock for concurrence safe
 	buf  *bufio.Writer
 	size int
 
+	// outputPath is the path of f. If pattern is set, it's rendered from it.
 	outputPath string
+	pattern    string
 	f          *os.File
 
-	c chan *os.File
+	rotate     RotateConfig
+	written    int64 // size of f, including buffered bytes
+	nextRotate time.Time
+
+	retired *retiredQueue
+
+	// closed is set by Close, which then closes retired and stop and waits
+	// for the background goroutines in wg.
+	closed bool
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// retiredFile is a file replaced by a rotation or ReOpen.
+type retiredFile struct {
+	f *os.File
+	// name is the path f was rotated to, or empty if it was replaced by
+	// ReOpen, which doesn't know where the file went.
+	name string
+	// current is the path of the file replacing it.
+	current string
 }
 
-// Buffer wraps a WriteSyncer with bufio
+// retiredQueue hands retired files to the goroutine cleaning them up.
+// Adding to it never blocks, so that writes don't wait for compression.
+type retiredQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	files  []retiredFile
+	closed bool
+}
+
+func newRetiredQueue() *retiredQueue {
+	q := &retiredQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *retiredQueue) put(rf retiredFile) {
+	q.mu.Lock()
+	q.files = append(q.files, rf)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+func (q *retiredQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// take waits for retired files and returns all of them. It reports false
+// once the queue is closed and empty.
+func (q *retiredQueue) take() ([]retiredFile, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.files) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	files := q.files
+	q.files = nil
+	return files, len(files) > 0
+}
+
+// Buffer wraps a WriteSyncer with bufio, and flushes it every flush seconds
+// in the background until Close.
 func Buffer(f *os.File, size, flush int, outputPath string) WriteSyncer {
+	return newBufWriterSync(f, size, flush, outputPath, "", RotateConfig{})
+}
+
+// RotatingBuffer opens the file at outputPath and wraps it like Buffer, but
+// also rotates it once it grows past rc.MaxSize, and every rc.Interval.
+// Time-based rotation happens on the first write of a new period, or on the
+// next background flush if nothing is written, so a quiet file is rotated
+// within flush seconds of the end of its period.
+//
+// If outputPath contains strftime-style verbs (%Y, %m, %d, %H, %M, %S and
+// %%), it's a pattern rendered from rc.Clock, such as "app-%Y%m%d-%H.log",
+// and time-based rotation moves on to the newly rendered path. Otherwise,
+// the file is renamed to <name>-<timestamp><ext> in the same directory (for
+// example, app-2006-01-02T15-04-05.000.log) and a new file is opened in its
+// place.
+//
+// Rotated files beyond rc.MaxBackups, or older than rc.MaxAge, are removed
+// in the background after each rotation, once the rotated file has been
+// compressed if rc.Compress is set.
+func RotatingBuffer(outputPath string, size, flush int, rc RotateConfig) (WriteSyncer, error) {
+	if rc.Clock == nil {
+		rc.Clock = DefaultClock
+	}
+	var pattern string
+	if strings.Contains(outputPath, "%") {
+		pattern = outputPath
+		outputPath = renderPath(pattern, rc.Clock.Now())
+		if rc.Interval == RotateNever {
+			rc.Interval = patternInterval(pattern)
+		}
+	}
+	f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return newBufWriterSync(f, size, flush, outputPath, pattern, rc), nil
+}
+
+func newBufWriterSync(f *os.File, size, flush int, outputPath, pattern string, rc RotateConfig) *bufWriterSync {
+	if rc.Clock == nil {
+		rc.Clock = DefaultClock
+	}
 	bw := &bufWriterSync{
 		buf:  bufio.NewWriterSize(f, size),
 		size: size,
 
 		outputPath: outputPath,
+		pattern:    pattern,
 		f:          f,
 
-		c: make(chan *os.File),
+		rotate:     rc,
+		written:    fileSize(f),
+		nextRotate: rc.Interval.next(rc.Clock.Now()),
+
+		retired: newRetiredQueue(),
+		stop:    make(chan struct{}),
 	}
 
-	go cleanOldFile(bw.c)
+	retention := outputPath
+	if pattern != "" {
+		retention = pattern
+	}
+	bw.wg.Add(2)
+	go func() {
+		defer bw.wg.Done()
+		cleanOldFile(bw.retired, retention, rc)
+	}()
+	go func() {
+		defer bw.wg.Done()
+		bw.flushEvery(time.Duration(flush) * time.Second)
+	}()
 
-	w := Lock(bw) // need lock for concurrence safe
+	return bw
+}
 
-	go func() {
-		ticker := time.NewTicker(time.Duration(flush) * time.Second)
-		for range ticker.C {
-			w.Sync()
+func (w *bufWriterSync) flushEvery(d time.Duration) {
+	ticker := w.rotate.Clock.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.tick()
+		case <-w.stop:
+			return
 		}
-	}()
+	}
+}
 
-	return w
+// tick flushes the buffer, first rotating the file if its period has ended
+// without a write to rotate it.
+func (w *bufWriterSync) tick() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	if due, bySize := w.needsRotation(0); due {
+		w.rotate.handleError(w.rotateFile(bySize))
+	}
+	w.buf.Flush()
 }
 
 func (w *bufWriterSync) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
 	return w.buf.Flush()
 }
 
 func (w *bufWriterSync) Write(p []byte) (written int, err error) {
-	return w.buf.Write(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errClosed
+	}
+	if due, bySize := w.needsRotation(len(p)); due {
+		// If rotation fails, keep writing to the current file.
+		err = w.rotateFile(bySize)
+	}
+	written, werr := w.buf.Write(p)
+	w.written += int64(written)
+	return written, multierr.Append(err, werr)
+}
+
+func (w *bufWriterSync) ReOpen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	return w.reopen("")
 }
 
-func (w *bufWriterSync) ReOpen() (err error) {
-	w.Sync()
-	w.c <- w.f // non-blocking here for avoiding stuck all log write
+// Close flushes the buffer, stops the background goroutines once they've
+// finished with the rotated files, and closes the file.
+func (w *bufWriterSync) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.buf.Flush()
+	w.retired.close()
+	w.mu.Unlock()
+
+	close(w.stop)
+	w.wg.Wait()
+	return multierr.Append(err, w.f.Close())
+}
+
+// reopen opens outputPath in place of f, which has been moved to retired. It
+// must be called with mu held.
+func (w *bufWriterSync) reopen(retired string) error {
+	w.buf.Flush()
 	f, err := os.OpenFile(w.outputPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
 	if err != nil {
-		return
+		return err
 	}
+	w.retired.put(retiredFile{f: w.f, name: retired, current: w.outputPath})
 	w.buf = bufio.NewWriterSize(f, w.size)
 	w.f = f
+	w.written = fileSize(f)
 	return nil
 }
 
-// cleanOldFile will close, sync, drop page_cache
-func cleanOldFile(c chan *os.File) {
-	for f := range c {
-		info, err := f.Stat()
+// needsRotation reports whether writing n bytes must rotate the file first,
+// and whether that's because of its size.
+func (w *bufWriterSync) needsRotation(n int) (due, bySize bool) {
+	bySize = w.rotate.MaxSize > 0 && w.written > 0 && w.written+int64(n) > w.rotate.MaxSize
+	due = bySize || (w.rotate.Interval != RotateNever && !w.rotate.Clock.Now().Before(w.nextRotate))
+	return due, bySize
+}
+
+func (w *bufWriterSync) rotateFile(bySize bool) error {
+	now := w.rotate.Clock.Now()
+	w.nextRotate = w.rotate.Interval.next(now)
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.pattern != "" {
+		if path := renderPath(w.pattern, now); path != w.outputPath {
+			retired := w.outputPath
+			w.outputPath = path
+			return w.reopen(retired)
+		}
+		if !bySize {
+			// The pattern renders the same path for the new period.
+			return nil
+		}
+	}
+	if w.written == 0 {
+		// Don't keep empty files around.
+		return nil
+	}
+	retired := backupName(w.outputPath, now)
+	if err := os.Rename(w.outputPath, retired); err != nil {
+		return err
+	}
+	return w.reopen(retired)
+}
+
+func fileSize(f *os.File) int64 {
+	info, err := f.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// backupName returns an unused name to rotate the file at outputPath to.
+func backupName(outputPath string, t time.Time) string {
+	prefix, ext := backupPrefixAndExt(outputPath)
+	for {
+		name := prefix + t.Format(backupTimeFormat) + ext
+		if !exists(name) && !exists(name+compressedExt) {
+			return name
+		}
+		// Rotating more than once a millisecond; pretend time has passed.
+		t = t.Add(time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
+
+func backupPrefixAndExt(outputPath string) (prefix, ext string) {
+	ext = filepath.Ext(outputPath)
+	return strings.TrimSuffix(outputPath, ext) + "-", ext
+}
+
+type backup struct {
+	path string
+	t    time.Time
+}
+
+// listBackups returns the rotated files of outputPath other than current,
+// newest first. The timestamps in their names are in loc, the location of
+// the clock they were rotated by.
+func listBackups(outputPath, current string, loc *time.Location) ([]backup, error) {
+	if strings.Contains(outputPath, "%") {
+		return listPatternBackups(outputPath, current)
+	}
+	infos, err := ioutil.ReadDir(filepath.Dir(outputPath))
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := backupPrefixAndExt(filepath.Base(outputPath))
+
+	var backups []backup
+	for _, info := range infos {
+		name := strings.TrimSuffix(info.Name(), compressedExt)
+		if info.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := name[len(prefix) : len(name)-len(ext)]
+		t, err := time.ParseInLocation(backupTimeFormat, ts, loc)
 		if err != nil {
-			f.Close()
 			continue
 		}
-		size := info.Size()
+		backups = append(backups, backup{filepath.Join(filepath.Dir(outputPath), info.Name()), t})
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+// listPatternBackups lists the renderings of a strftime-style pattern, and
+// the files they were rotated to by size, compressed or not. They're dated
+// by their modification times.
+func listPatternBackups(pattern, current string) ([]backup, error) {
+	prefix, ext := backupPrefixAndExt(pattern)
+	rendered := patternGlob(pattern)
+	rotated := patternGlob(prefix) + timeFormatGlob(backupTimeFormat) + patternGlob(ext)
+
+	var backups []backup
+	for _, glob := range []string{rendered, rendered + compressedExt, rotated, rotated + compressedExt} {
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range matches {
+			if path == current {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			backups = append(backups, backup{path, info.ModTime()})
+		}
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+func sortBackups(backups []backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].t.After(backups[j].t)
+	})
+}
+
+// removeOldBackups enforces the retention limits of rc.
+func removeOldBackups(outputPath, current string, rc RotateConfig, now time.Time) error {
+	if rc.MaxBackups <= 0 && rc.MaxAge <= 0 {
+		return nil
+	}
+	backups, err := listBackups(outputPath, current, now.Location())
+	if err != nil {
+		return err
+	}
+	for i, b := range backups {
+		if (rc.MaxBackups > 0 && i >= rc.MaxBackups) || (rc.MaxAge > 0 && now.Sub(b.t) > rc.MaxAge) {
+			err = multierr.Append(err, os.Remove(b.path))
+		}
+	}
+	return err
+}
+
+// cleanOldFile will close, sync, drop page_cache, compress if configured,
+// and remove the rotated files which are no longer retained, until q is
+// closed.
+func cleanOldFile(q *retiredQueue, outputPath string, rc RotateConfig) {
+	for {
+		files, ok := q.take()
+		if !ok {
+			return
+		}
+		for _, rf := range files {
+			closeOldFile(rf.f)
+			if rc.Compress && rf.name != "" {
+				// Files queued behind others may be removed by retention
+				// before they're compressed.
+				if err := compressFile(rf.name); !os.IsNotExist(err) {
+					rc.handleError(err)
+				}
+			}
+		}
+		current := files[len(files)-1].current
+		rc.handleError(removeOldBackups(outputPath, current, rc, rc.Clock.Now()))
+	}
+}
 
-		f.Sync()
-		dropCache(f, 0, size)
+const compressedExt = ".gz"
+
+// compressFile gzips the file at name to name+".gz", keeping its modification
+// time, and removes the original once the compressed copy is synced. On
+// failure, the original is left in place.
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	gzName := name + compressedExt
+	dst, err := os.OpenFile(gzName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	err = multierr.Combine(err, gz.Close(), dst.Sync(), dst.Close())
+	if err != nil {
+		os.Remove(gzName)
+		return err
+	}
+
+	// Pattern backups are dated by their modification times.
+	os.Chtimes(gzName, info.ModTime(), info.ModTime())
+	return os.Remove(name)
+}
+
+func closeOldFile(f *os.File) {
+	info, err := f.Stat()
+	if err != nil {
 		f.Close()
+		return
 	}
+	size := info.Size()
+
+	f.Sync()
+	dropCache(f, 0, size)
+	f.Close()
 }
 
 const posix_fadv_dontneed = 4
@@ -1,8 +1,12 @@
 package zapcore_test
 
 import (
+	"compress/gzip"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -138,3 +142,438 @@ func TestIoCore_Flush(t *testing.T) {
 		"Unexpected log output.",
 	)
 }
+
+func writeLines(t *testing.T, ws WriteSyncer, n int) {
+	for i := 0; i < n; i++ {
+		_, err := ws.Write([]byte("0123456789\n"))
+		require.NoError(t, err, "Unexpected error writing.")
+	}
+	require.NoError(t, ws.Sync(), "Unexpected error syncing.")
+}
+
+func backupNames(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	require.NoError(t, err, "Failed to list backups.")
+	return matches
+}
+
+func TestRotatingBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	ws, err := RotatingBuffer(path, 1024, 60, RotateConfig{MaxSize: 25, MaxBackups: 2})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+	writeLines(t, ws, 2)
+	assert.Empty(t, backupNames(t, dir), "Unexpected rotation below MaxSize.")
+
+	// Each rotation leaves two lines behind, so writing eight lines rotates
+	// three times; retention keeps the newest two rotated files.
+	writeLines(t, ws, 6)
+	var backups []string
+	for i := 0; i < 100; i++ {
+		if backups = backupNames(t, dir); len(backups) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 2, len(backups), "Expected MaxBackups rotated files to be kept.")
+	for _, b := range backups {
+		contents, err := ioutil.ReadFile(b)
+		require.NoError(t, err, "Failed to read rotated file.")
+		assert.Equal(t, strings.Repeat("0123456789\n", 2), string(contents), "Unexpected rotated file contents.")
+	}
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "Failed to read log file.")
+	assert.Equal(t, strings.Repeat("0123456789\n", 2), string(contents), "Unexpected current file contents.")
+}
+
+func TestRotatingBufferMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "app-2001-02-03T04-05-06.000.log")
+	unrelated := filepath.Join(dir, "app-notatime.log")
+	for _, name := range []string{old, unrelated} {
+		require.NoError(t, ioutil.WriteFile(name, nil, 0644), "Failed to create file.")
+	}
+
+	path := filepath.Join(dir, "app.log")
+	ws, err := RotatingBuffer(path, 1024, 60, RotateConfig{MaxSize: 15, MaxAge: time.Hour})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+	writeLines(t, ws, 2)
+	for i := 0; i < 100; i++ {
+		if _, err := os.Stat(old); os.IsNotExist(err) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err), "Expected rotated files older than MaxAge to be removed.")
+	assert.Equal(t, 2, len(backupNames(t, dir)), "Expected recent and unrelated files to be kept.")
+}
+
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func (c *manualClock) NewTicker(d time.Duration) *time.Ticker {
+	return time.NewTicker(d)
+}
+
+// tickingClock is a manualClock whose tickers tick every millisecond, so that
+// background flushes run without waiting for them.
+type tickingClock struct {
+	manualClock
+}
+
+func (c *tickingClock) NewTicker(time.Duration) *time.Ticker {
+	return time.NewTicker(time.Millisecond)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readDir(t *testing.T, dir string) map[string]string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err, "Failed to list log files.")
+	files := make(map[string]string, len(infos))
+	for _, info := range infos {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		require.NoError(t, err, "Failed to read log file.")
+		files[info.Name()] = string(contents)
+	}
+	return files
+}
+
+func TestRotatingBufferPattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &manualClock{now: time.Date(2026, 10, 17, 13, 59, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app-%Y%m%d-%H.log"), 1024, 60, RotateConfig{
+		Interval: RotateHourly,
+		Clock:    clock,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	ws.Write([]byte("a\n"))
+	clock.Add(59 * time.Second)
+	ws.Write([]byte("b\n"))
+	clock.Add(time.Second)
+	ws.Write([]byte("c\n"))
+	require.NoError(t, ws.Sync(), "Unexpected error syncing.")
+
+	assert.Equal(t, map[string]string{
+		"app-20261017-13.log": "a\nb\n",
+		"app-20261017-14.log": "c\n",
+	}, readDir(t, dir), "Expected rotation at the hour boundary.")
+}
+
+func TestRotatingBufferPatternRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	unrelated := []string{"other-app.log", "important.log", "x.log", "20261.log", "app-error.log"}
+	for _, name := range unrelated {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("keep\n"), 0644), "Failed to create file.")
+	}
+
+	clock := &manualClock{now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "%Y%m%d.log"), 1024, 60, RotateConfig{
+		MaxSize:    10,
+		Interval:   RotateDaily,
+		MaxBackups: 1,
+		Clock:      clock,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	// Two size rotations on the 17th, then a daily one.
+	for _, line := range []string{"0123456789\n", "0123456789\n", "0123456789\n"} {
+		ws.Write([]byte(line))
+		clock.Add(time.Second)
+	}
+	clock.Add(time.Hour)
+	ws.Write([]byte("c\n"))
+	require.NoError(t, ws.Close(), "Unexpected error closing.")
+
+	files := readDir(t, dir)
+	for _, name := range unrelated {
+		assert.Equal(t, "keep\n", files[name], "Expected %q to be left alone.", name)
+	}
+	assert.Equal(t, "c\n", files["20261018.log"], "Unexpected current file contents.")
+	assert.Equal(t, len(unrelated)+2, len(files), "Expected MaxBackups rotated files to be kept, got %v.", files)
+}
+
+func TestRotatingBufferPatternInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &manualClock{now: time.Date(2026, 10, 31, 23, 0, 0, 0, time.Local)}
+	var syncers []WriteSyncer
+	for _, pattern := range []string{"day-%Y%m%d.log", "month-%Y%m.log"} {
+		ws, err := RotatingBuffer(filepath.Join(dir, pattern), 1024, 60, RotateConfig{Clock: clock})
+		require.NoError(t, err, "Failed to open log file.")
+		syncers = append(syncers, ws)
+	}
+	for _, ws := range syncers {
+		ws.Write([]byte("a\n"))
+	}
+	clock.Add(time.Hour)
+	for _, ws := range syncers {
+		ws.Write([]byte("b\n"))
+	}
+	clock.Add(24 * time.Hour)
+	for _, ws := range syncers {
+		ws.Write([]byte("c\n"))
+		require.NoError(t, ws.Close(), "Unexpected error closing.")
+	}
+
+	assert.Equal(t, map[string]string{
+		"day-20261031.log": "a\n",
+		"day-20261101.log": "b\n",
+		"day-20261102.log": "c\n",
+		"month-202610.log": "a\n",
+		"month-202611.log": "b\nc\n",
+	}, readDir(t, dir), "Expected the interval to be inferred from the pattern.")
+}
+
+func TestRotatingBufferDaily(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &manualClock{now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app.log"), 1024, 60, RotateConfig{
+		Interval: RotateDaily,
+		Clock:    clock,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	ws.Write([]byte("a\n"))
+	clock.Add(time.Hour)
+	ws.Write([]byte("b\n"))
+	clock.Add(24 * time.Hour)
+	ws.Write([]byte("c\n"))
+	require.NoError(t, ws.Sync(), "Unexpected error syncing.")
+
+	assert.Equal(t, map[string]string{
+		"app-2026-10-18T00-00-00.000.log": "a\n",
+		"app-2026-10-19T00-00-00.000.log": "b\n",
+		"app.log":                         "c\n",
+	}, readDir(t, dir), "Expected rotation at midnight.")
+}
+
+func TestRotatingBufferRotatesWithoutWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &tickingClock{manualClock{now: time.Date(2026, 10, 17, 13, 59, 0, 0, time.Local)}}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app.log"), 1024, 60, RotateConfig{
+		Interval: RotateHourly,
+		Clock:    clock,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	ws.Write([]byte("a\n"))
+	clock.Add(time.Minute)
+	backup := filepath.Join(dir, "app-2026-10-17T14-00-00.000.log")
+	for i := 0; i < 100 && !fileExists(backup); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, ws.Close(), "Unexpected error closing.")
+
+	assert.Equal(t, map[string]string{
+		"app-2026-10-17T14-00-00.000.log": "a\n",
+		"app.log":                         "",
+	}, readDir(t, dir), "Expected a background flush to rotate at the end of the period.")
+}
+
+func TestRotatingBufferMaxAgeClockLocation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	// Backup names are in the clock's location, whatever the local one is.
+	loc := time.FixedZone("UTC-5", -5*60*60)
+	recent := filepath.Join(dir, "app-2026-10-17T11-30-00.000.log")
+	old := filepath.Join(dir, "app-2026-10-17T10-30-00.000.log")
+	for _, name := range []string{recent, old} {
+		require.NoError(t, ioutil.WriteFile(name, nil, 0644), "Failed to create file.")
+	}
+
+	clock := &manualClock{now: time.Date(2026, 10, 17, 12, 0, 0, 0, loc)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app.log"), 1024, 60, RotateConfig{
+		MaxSize: 15,
+		MaxAge:  time.Hour,
+		Clock:   clock,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	writeLines(t, ws, 2)
+	require.NoError(t, ws.Close(), "Unexpected error closing.")
+
+	assert.True(t, fileExists(recent), "Expected rotated files younger than MaxAge to be kept.")
+	assert.False(t, fileExists(old), "Expected rotated files older than MaxAge to be removed.")
+}
+
+func TestRotatingBufferCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	clock := &manualClock{now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app.log"), 1024, 60, RotateConfig{
+		Interval:   RotateDaily,
+		MaxBackups: 1,
+		Clock:      clock,
+		Compress:   true,
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	ws.Write([]byte("a\n"))
+	clock.Add(time.Hour)
+	ws.Write([]byte("b\n"))
+	clock.Add(24 * time.Hour)
+	ws.Write([]byte("c\n"))
+	require.NoError(t, ws.Sync(), "Unexpected error syncing.")
+
+	rotated := filepath.Join(dir, "app-2026-10-19T00-00-00.000.log")
+	// Wait for compression and retention to catch up with both rotations.
+	for i := 0; i < 100; i++ {
+		if files := readDir(t, dir); len(files) == 2 && files[filepath.Base(rotated)+".gz"] != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	files := readDir(t, dir)
+	assert.Equal(t, 2, len(files), "Expected one compressed backup and the current file, got %v.", files)
+	assert.Equal(t, "c\n", files["app.log"], "Unexpected current file contents.")
+
+	f, err := os.Open(rotated + ".gz")
+	require.NoError(t, err, "Failed to open compressed file.")
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err, "Failed to read compressed file.")
+	contents, err := ioutil.ReadAll(gz)
+	require.NoError(t, err, "Failed to decompress file.")
+	assert.Equal(t, "b\n", string(contents), "Unexpected compressed file contents.")
+}
+
+func TestRotatingBufferCompressError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	// A directory in the way of the compressed file makes compression fail.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app-17.log.gz"), 0755), "Failed to create dir.")
+
+	errs := make(chan error, 1)
+	clock := &manualClock{now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app-%d.log"), 1024, 60, RotateConfig{
+		Interval:     RotateDaily,
+		Clock:        clock,
+		Compress:     true,
+		ErrorHandler: func(err error) { errs <- err },
+	})
+	require.NoError(t, err, "Failed to open log file.")
+	defer ws.Close()
+
+	ws.Write([]byte("a\n"))
+	clock.Add(time.Hour)
+	ws.Write([]byte("b\n"))
+	require.NoError(t, ws.Sync(), "Unexpected error syncing.")
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err, "Expected a compression error.")
+	case <-time.After(time.Second):
+		t.Fatal("Expected compression errors to be reported.")
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "app-17.log"))
+	require.NoError(t, err, "Expected the original to be kept.")
+	assert.Equal(t, "a\n", string(contents), "Unexpected rotated file contents.")
+}
+
+func TestRotatingBufferClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	ws, err := RotatingBuffer(path, 1024, 60, RotateConfig{MaxSize: 15, Compress: true})
+	require.NoError(t, err, "Failed to open log file.")
+	ws.Write([]byte("0123456789\n"))
+	ws.Write([]byte("0123456789\n"))
+
+	require.NoError(t, ws.Close(), "Unexpected error closing.")
+	files := readDir(t, dir)
+	assert.Equal(t, 2, len(files), "Expected Close to wait for compression, got %v.", files)
+	assert.Equal(t, "0123456789\n", files["app.log"], "Expected Close to flush the buffer.")
+
+	_, err = ws.Write([]byte("after\n"))
+	assert.Error(t, err, "Expected writes after Close to fail.")
+	assert.NoError(t, ws.Sync(), "Expected Sync after Close to be a no-op.")
+	assert.NoError(t, ws.Close(), "Expected closing again to be a no-op.")
+}
+
+func TestRotatingBufferDoesNotWaitForCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zapcore-test-rotate")
+	require.NoError(t, err, "Failed to create temp dir.")
+	defer os.RemoveAll(dir)
+
+	// Hold up the cleanup goroutine in the error handler.
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "app-17.log.gz"), 0755), "Failed to create dir.")
+	gate := make(chan struct{})
+	clock := &manualClock{now: time.Date(2026, 10, 17, 23, 0, 0, 0, time.Local)}
+	ws, err := RotatingBuffer(filepath.Join(dir, "app-%d.log"), 1024, 60, RotateConfig{
+		Interval:     RotateDaily,
+		Clock:        clock,
+		Compress:     true,
+		ErrorHandler: func(error) { <-gate },
+	})
+	require.NoError(t, err, "Failed to open log file.")
+
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for _, line := range []string{"a\n", "b\n", "c\n"} {
+			ws.Write([]byte(line))
+			clock.Add(24 * time.Hour)
+		}
+	}()
+	select {
+	case <-written:
+	case <-time.After(time.Second):
+		t.Fatal("Expected writes not to wait for the cleanup of rotated files.")
+	}
+	close(gate)
+	require.NoError(t, ws.Close(), "Unexpected error closing.")
+}
@@ -138,3 +138,11 @@ func (w testingWriter) Write(p []byte) (n int, err error) {
 func (w testingWriter) Sync() error {
 	return nil
 }
+
+func (w testingWriter) ReOpen() error {
+	return nil
+}
+
+func (w testingWriter) Close() error {
+	return nil
+}
@@ -169,3 +169,7 @@ func (co *contextObserver) Sync() error {
 func (co *contextObserver) ReOpen() error {
 	return nil
 }
+
+func (co *contextObserver) Close() error {
+	return nil
+}